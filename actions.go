@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Capture modes: where an action takes its text from
+const (
+	captureSelection = "selection"  // Copy the selected text
+	captureSelectAll = "select_all" // Select everything in the focused field and copy it
+	captureClipboard = "clipboard"  // Use the text already on the clipboard
+)
+
+var captureModes = []string{captureSelection, captureSelectAll, captureClipboard}
+
+// Transforms: what an action does with the captured text
+const (
+	transformTranslate = "translate" // Translate into the targets of the action
+	transformPrompt    = "prompt"    // Send the prompt template of the action, rendered with the text, to the LLM
+)
+
+var transforms = []string{transformTranslate, transformPrompt}
+
+// Output modes: where the result goes. Every mode but paste and preview leaves the result on the clipboard.
+const (
+	outputPaste        = "paste"        // Paste over the captured text, the clipboard is restored afterwards
+	outputClipboard    = "clipboard"    // Only put the result on the clipboard
+	outputAlert        = "alert"        // Show the result in an alert
+	outputNotification = "notification" // Show the result in a desktop notification
+	outputPreview      = "preview"      // Show the result in a window to review and copy
+)
+
+var outputModes = []string{outputPaste, outputClipboard, outputAlert, outputNotification, outputPreview}
+
+// Targets resolved from the config when an action runs
+const (
+	targetSelected          = "@selected"           // The selected languages
+	targetClipboardLanguage = "@clipboard_language" // The language of the clipboard translation
+)
+
+// Action is what a hotkey does: capture text, transform it and output the result.
+// Actions in config replace built-in actions with the same name.
+type Action struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Capture     string   `json:"capture"`
+	Transform   string   `json:"transform"`
+	Targets     []string `json:"targets,omitempty"` // Language codes or @selected/@clipboard_language, @selected when empty
+	Prompt      string   `json:"prompt,omitempty"`  // text/template for the prompt transform, see promptData
+	Model       string   `json:"model,omitempty"`   // Model used instead of the configured one
+	Output      string   `json:"output"`
+	Hotkey      string   `json:"hotkey,omitempty"` // Default hotkey, a hotkey recorded in the window replaces it
+}
+
+// Actions available without any configuration
+var builtinActions = []Action{
+	{
+		Name: actionTranslate, Description: "Translate selected text to English only",
+		Capture: captureSelection, Transform: transformTranslate, Targets: []string{"en"}, Output: outputPaste,
+	},
+	{
+		Name: actionDualTranslate, Description: "Select all text and translate to the selected languages",
+		Capture: captureSelectAll, Transform: transformTranslate, Targets: []string{targetSelected}, Output: outputPaste,
+	},
+	{
+		Name: actionClipboardTranslate, Description: "Translate selected text to the clipboard language (copies to clipboard & shows alert)",
+		Capture: captureSelection, Transform: transformTranslate, Targets: []string{targetClipboardLanguage}, Output: outputAlert,
+	},
+}
+
+// PromptRunner is implemented by LLM backends that can answer any prompt, used by the prompt transform
+type PromptRunner interface {
+	RunPrompt(prompt string) (string, error)
+}
+
+// actionResult is the outcome of the transform step
+type actionResult struct {
+	Text    string
+	Title   string   // Title of alerts, notifications and the preview window, the language name when skipped
+	Targets []string // Codes of the languages translated into
+	Skipped bool     // The text is already in the target language
+}
+
+// Find an action by name, actions in config come before built-in ones
+func findAction(config Config, name string) (Action, bool) {
+	for _, action := range config.Actions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	for _, action := range builtinActions {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// Get the names of everything a hotkey can be bound to, built-in ones first
+func actionNames(config Config) []string {
+	names := slices.Clone(hotkeyActions)
+	for _, action := range config.Actions {
+		if !contains(names, action.Name) {
+			names = append(names, action.Name)
+		}
+	}
+	return names
+}
+
+// Check if a hotkey can be bound to name
+func isKnownAction(config Config, name string) bool {
+	return contains(actionNames(config), name)
+}
+
+// Short description of an action for the hotkey list
+func actionDescription(config Config, name string) string {
+	if name == actionNextProfile {
+		return "Switch to the next profile"
+	}
+	if action, ok := findAction(config, name); ok && action.Description != "" {
+		return action.Description
+	}
+	return name
+}
+
+// Sort hotkey action names with the built-in actions first, in the order they are shown in the UI
+func sortActionNames(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := slices.Index(hotkeyActions, names[i]), slices.Index(hotkeyActions, names[j])
+		if a >= 0 || b >= 0 {
+			return a >= 0 && (b < 0 || a < b)
+		}
+		return names[i] < names[j]
+	})
+}
+
+// Look up the target languages of an action
+func actionTargets(config Config, action Action) []Language {
+	codes := action.Targets
+	if len(codes) == 0 {
+		codes = []string{targetSelected}
+	}
+
+	var targets []Language
+	for _, code := range codes {
+		switch code {
+		case targetSelected:
+			targets = append(targets, languagesForCodes(config, config.SelectedLanguages)...)
+		case targetClipboardLanguage:
+			language, ok := findLanguage(config, config.ClipboardLanguage)
+			if !ok {
+				language, _ = findLanguage(config, "vi") // Fallback
+			}
+			targets = append(targets, language)
+		default:
+			if language, ok := findLanguage(config, code); ok {
+				targets = append(targets, language)
+			}
+		}
+	}
+	return targets
+}
+
+// Run an action from capture to output, for hotkeys and D-Bus calls
+func runAction(name string) {
+	// Use one config snapshot for the whole action
+	config := currentConfig()
+	action, ok := findAction(config, name)
+	if !ok {
+		logf("❌ Unknown action: %s\n", name)
+		return
+	}
+	if action.Model != "" {
+		config.Model = action.Model
+	}
+	config.PromptAction = action.Name
+	if !platformReady() {
+		return
+	}
+
+	// Save the clipboard so it can be put back after pasting
+	savedClipboard := backupClipboard()
+	text, err := captureText(action, savedClipboard)
+	if err == errNoSelection {
+		logln("⚠️  No selection")
+		reportActionError(action, "Nothing to translate", err)
+		return
+	}
+	if err != nil {
+		logf("❌ Error copying text: %v\n", err)
+		reportActionError(action, "Copy failed", err)
+		return
+	}
+	if (action.Output == outputPaste || action.Output == outputPreview) && !config.KeepTranslationInClipboard {
+		defer restoreClipboard(savedClipboard)
+	}
+
+	logf("📝 Copied text: \"%s\"\n", text)
+	logf("📏 Text length: %d characters\n", len(text))
+
+	// Detect the source language so languages it is already in are not translated
+	source := detectSourceLanguage(config, text)
+	var result actionResult
+	if action.Transform == transformPrompt {
+		result, err = runActionPrompt(config, action, text, source)
+	} else {
+		result, err = translateForAction(config, action, text, source)
+	}
+	if err != nil {
+		logf("❌ %s error: %v\n", action.Name, err)
+		reportActionError(action, "Translation failed", err)
+		return
+	}
+
+	entry := historyEntry{Action: action.Name, SourceLanguage: source, Targets: result.Targets, Text: text, Result: result.Text, Skipped: result.Skipped}
+	if result.Skipped {
+		entry.Result = ""
+		addHistory(entry)
+		message := fmt.Sprintf("Text is already in %s", result.Title)
+		switch action.Output {
+		case outputAlert:
+			showAlert("Notification", message)
+		case outputNotification, outputPreview:
+			showNotification("Notification", message)
+		}
+		return
+	}
+	addHistory(entry)
+
+	if err := outputResult(action, result); err != nil {
+		logf("❌ Error writing the result: %v\n", err)
+		reportActionError(action, "Translation failed", err)
+		return
+	}
+	logf("✨ %s completed!\n", action.Name)
+}
+
+// Get the text an action works on
+func captureText(action Action, saved *ClipboardContent) (string, error) {
+	if action.Capture == captureClipboard {
+		logln("📋 Reading clipboard...")
+		text, err := systemClipboard.Read()
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(text) == "" {
+			return "", errNoSelection
+		}
+		return text, nil
+	}
+
+	// Add a small delay to ensure hotkey processing is complete
+	time.Sleep(150 * time.Millisecond)
+
+	if action.Capture == captureSelectAll {
+		logln("📋 Selecting all text and copying...")
+		if err := systemKeys.SelectAll(); err != nil {
+			return "", fmt.Errorf("select all: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond) // Wait for select all to complete
+	} else {
+		logln("📋 Copying selected text...")
+	}
+
+	// Copy the selection with the platform shortcut and wait for it on the clipboard
+	return copySelection(saved)
+}
+
+// Translate the text into the targets of an action. A single target may be switched or
+// skipped for text already in it, several targets are joined like the dual translation.
+func translateForAction(config Config, action Action, text string, source string) (actionResult, error) {
+	targets := actionTargets(config, action)
+	if len(targets) == 0 {
+		return actionResult{}, fmt.Errorf("no valid languages selected for translation")
+	}
+
+	translator, err := newTranslator(config)
+	if err != nil {
+		return actionResult{}, err
+	}
+
+	if len(targets) == 1 {
+		target, skip := resolveTarget(config, source, targets[0])
+		result := actionResult{Title: fmt.Sprintf("Translation (%s)", target.Label), Targets: []string{target.Code}}
+		if skip {
+			logf("⏭️ Text is already in %s, skipping translation\n", target.Name)
+			result.Title = target.Name
+			result.Skipped = true
+			return result, nil
+		}
+
+		logf("🌐 Translating from %q to %s with %s...\n", source, target.Name, config.Provider)
+		playLoadingSound()
+		result.Text, err = translator.Translate(text, target)
+		if err != nil {
+			return result, err
+		}
+		logf("✅ Translated text: \"%s\"\n", result.Text)
+		return result, nil
+	}
+
+	playLoadingSound()
+	var translations []string
+	var codes []string
+	translatedCount := 0
+	for _, result := range translateFromSource(config, text, source, targets) {
+		codes = append(codes, result.Language.Code)
+		if result.Err != nil {
+			// Show the failure inline so the missing language is noticed
+			logf("❌ %s translation error: %v\n", result.Language.Label, result.Err)
+			translations = append(translations, fmt.Sprintf("[%s]: translation failed: %s", result.Language.Label, translationFailureReason(result.Err)))
+			continue
+		}
+
+		// Format with or without prefix based on setting
+		if config.IncludePrefix {
+			translations = append(translations, fmt.Sprintf("[%s]: %s", result.Language.Label, result.Text))
+		} else {
+			translations = append(translations, result.Text)
+		}
+		translatedCount++
+		logf("✅ %s: \"%s\"\n", result.Language.Label, result.Text)
+	}
+
+	// Keep the original text instead of replacing it with errors only
+	if translatedCount == 0 {
+		return actionResult{}, fmt.Errorf("all %d languages failed", len(targets))
+	}
+	return actionResult{
+		Text:    strings.Join(translations, "\n----------------\n"),
+		Title:   "Translation",
+		Targets: codes,
+	}, nil
+}
+
+// Render the prompt template of an action and send it to the LLM of the config
+func runActionPrompt(config Config, action Action, text string, source string) (actionResult, error) {
+	targets := actionTargets(config, action)
+	prompt, err := renderPrompt(action.Name, action.Prompt, newPromptData(config, text, source, targets))
+	if err != nil {
+		return actionResult{}, err
+	}
+	// Prompts that don't place the text themselves get it at the end
+	if !strings.Contains(action.Prompt, ".Text") {
+		prompt += "\n\n" + text
+	}
+
+	translator, err := newTranslator(config)
+	if err != nil {
+		return actionResult{}, err
+	}
+	runner, ok := translator.(PromptRunner)
+	if !ok {
+		return actionResult{}, fmt.Errorf("%s cannot run prompts, use an LLM provider", translator.Name())
+	}
+
+	logf("🤖 Running prompt of %s with %s...\n", action.Name, config.Provider)
+	playLoadingSound()
+	result, err := runner.RunPrompt(prompt)
+	if err != nil {
+		return actionResult{}, err
+	}
+	logf("✅ Result: \"%s\"\n", result)
+	return actionResult{Text: result, Title: actionDescription(config, action.Name)}, nil
+}
+
+// Put the result where the action wants it
+func outputResult(action Action, result actionResult) error {
+	if action.Output == outputPreview {
+		showPreview(result.Title, result.Text)
+		return nil
+	}
+
+	logln("📋 Writing result to clipboard...")
+	if err := systemClipboard.Write(result.Text); err != nil {
+		return err
+	}
+
+	switch action.Output {
+	case outputPaste:
+		logln("📝 Pasting result...")
+		return systemKeys.Paste()
+	case outputAlert:
+		showAlert(result.Title, result.Text)
+	case outputNotification:
+		showNotification(result.Title, result.Text)
+	}
+	return nil
+}
+
+// Tell the user about a failed action the way its output would have been shown
+func reportActionError(action Action, title string, err error) {
+	switch action.Output {
+	case outputAlert:
+		showAlert("Error", fmt.Sprintf("%s: %v", title, err))
+		broadcastError(title, err)
+	case outputNotification, outputPreview:
+		showNotification(title, err.Error())
+		broadcastError(title, err)
+	default:
+		notifyError(title, err)
+	}
+}
+
+// Variables used to check prompt templates when the config is validated
+var samplePromptData = promptData{Text: "Hello", Language: "English", Languages: []string{"English"}, SourceLanguage: "Vietnamese"}
+
+// Check the actions of a config, problems are reported for the actions field
+func validateActions(c Config, add func(field string, format string, args ...any)) {
+	seen := map[string]bool{}
+	for i, action := range c.Actions {
+		name := action.Name
+		if strings.TrimSpace(name) == "" {
+			add("actions", "action %d has no name", i+1)
+			continue
+		}
+		if seen[name] {
+			add("actions", "action name %q is used twice", name)
+		}
+		seen[name] = true
+		if name == actionNextProfile {
+			add("actions", "%s is reserved for switching profiles", name)
+		}
+
+		if !contains(captureModes, action.Capture) {
+			add("actions", "%s: unknown capture %q, use one of %s", name, action.Capture, strings.Join(captureModes, ", "))
+		}
+		if !contains(transforms, action.Transform) {
+			add("actions", "%s: unknown transform %q, use one of %s", name, action.Transform, strings.Join(transforms, ", "))
+		}
+		if !contains(outputModes, action.Output) {
+			add("actions", "%s: unknown output %q, use one of %s", name, action.Output, strings.Join(outputModes, ", "))
+		}
+		if action.Transform == transformPrompt {
+			if strings.TrimSpace(action.Prompt) == "" {
+				add("actions", "%s: the prompt transform needs a prompt", name)
+			} else if _, err := renderPrompt(name, action.Prompt, samplePromptData); err != nil {
+				add("actions", "%s: invalid prompt template: %v", name, err)
+			}
+		}
+		// The model replaces the one of the provider, so it has to be one the provider offers
+		if action.Model != "" && contains(translationProviders, c.Provider) && !isKnownModel(c, action.Model) {
+			add("actions", "%s: unknown model %q for %s", name, action.Model, c.Provider)
+		}
+		if action.Hotkey != "" {
+			if _, err := parseAccelerator(action.Hotkey); err != nil {
+				add("actions", "%s: invalid hotkey: %v", name, err)
+			}
+		}
+		for _, code := range action.Targets {
+			if code == targetSelected || code == targetClipboardLanguage {
+				continue
+			}
+			if _, ok := findLanguage(c, code); !ok {
+				add("actions", "%s: unknown language code %q", name, code)
+			}
+		}
+	}
+
+	names := slices.Collect(maps.Keys(c.Hotkeys))
+	sort.Strings(names)
+	for _, name := range names {
+		if !isKnownAction(c, name) {
+			add("hotkeys", "no action named %q", name)
+		}
+	}
+}
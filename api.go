@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Port of the local API when api_port is not set
+const defaultAPIPort = 8765
+
+// Shortest api_token accepted, shorter tokens are easy to guess by other local programs
+const minAPITokenLength = 16
+
+// Largest request body accepted by the API
+const maxAPIRequestBytes = 1 << 20
+
+// apiTranslateRequest is the body of POST /translate. Targets are language codes or labels,
+// the selected languages are used when empty. Profile and style change only this request.
+type apiTranslateRequest struct {
+	Text    string   `json:"text"`
+	Targets []string `json:"targets,omitempty"`
+	Profile string   `json:"profile,omitempty"`
+	Style   string   `json:"style,omitempty"`
+}
+
+// apiTranslation is the result for one target language, Error is set when it failed
+type apiTranslation struct {
+	Language string `json:"language"`
+	Label    string `json:"label"`
+	Text     string `json:"text,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type apiTranslateResponse struct {
+	SourceLanguage string           `json:"source_language,omitempty"`
+	Translations   []apiTranslation `json:"translations"`
+}
+
+type apiLanguage struct {
+	Language
+	Selected bool `json:"selected"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// How long a stopped API server waits for running requests
+const apiShutdownTimeout = 2 * time.Second
+
+// The running API server, restarted when api_enabled or api_port change
+var (
+	apiServerMu   sync.Mutex
+	apiServer     *http.Server
+	apiListener   net.Listener
+	apiServerAddr string
+)
+
+// Get the address the API listens on
+func apiAddr(config Config) string {
+	port := config.APIPort
+	if port == 0 {
+		port = defaultAPIPort
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+}
+
+// Start the API when it is enabled and keep it in line with later config changes
+func subscribeAPIServer() {
+	applyAPIServer(currentConfig())
+	subscribeConfig(applyAPIServer)
+}
+
+// Start, stop or move the API server to match the config
+func applyAPIServer(config Config) {
+	addr := ""
+	if config.APIEnabled {
+		if len(config.APIToken) < minAPITokenLength {
+			logf("❌ Local API not started: api_token must be at least %d characters\n", minAPITokenLength)
+		} else {
+			addr = apiAddr(config)
+		}
+	}
+
+	apiServerMu.Lock()
+	defer apiServerMu.Unlock()
+	if addr == apiServerAddr {
+		return
+	}
+
+	if apiServer != nil {
+		// Free the port right away, waiting for running requests must not block the caller,
+		// which is often the UI thread
+		apiListener.Close()
+		go func(server *http.Server) {
+			ctx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+			defer cancel()
+			server.Shutdown(ctx)
+			logln("🔌 Local API stopped")
+		}(apiServer)
+		apiServer, apiListener = nil, nil
+	}
+	apiServerAddr = addr
+	if addr == "" {
+		return
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logf("❌ Cannot start local API on %s: %v\n", addr, err)
+		apiServerAddr = ""
+		return
+	}
+	apiListener = listener
+	apiServer = &http.Server{
+		Handler:           newAPIHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func(server *http.Server) {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			logf("❌ Local API error: %v\n", err)
+		}
+	}(apiServer)
+	logf("🔌 Local API listening on http://%s\n", addr)
+}
+
+// Routes of the local API, every route needs the token
+func newAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /translate", handleAPITranslate)
+	mux.HandleFunc("GET /languages", handleAPILanguages)
+	mux.HandleFunc("GET /models", handleAPIModels)
+	mux.HandleFunc("GET /history", handleAPIHistory)
+	return requireAPIToken(mux)
+}
+
+// Check the "Authorization: Bearer <token>" header against api_token
+func requireAPIToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := currentConfig().APIToken
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("missing or wrong API token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// POST /translate: translate with the same pipeline as the dual translation hotkey
+func handleAPITranslate(w http.ResponseWriter, r *http.Request) {
+	var req apiTranslateRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes))
+	if err := decoder.Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("bad request body: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("text is required"))
+		return
+	}
+
+	config := currentConfig()
+	if req.Profile != "" {
+		if err := switchProfile(&config, req.Profile); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if req.Style != "" {
+		if !contains(promptStyleNames(), req.Style) {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown prompt style %q, use one of %s", req.Style, strings.Join(promptStyleNames(), ", ")))
+			return
+		}
+		config.PromptStyle = req.Style
+	}
+
+	targets := languagesForCodes(config, config.SelectedLanguages)
+	if len(req.Targets) > 0 {
+		targets = nil
+		for _, name := range req.Targets {
+			language, ok := lookupLanguage(config, strings.TrimSpace(name))
+			if !ok {
+				writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown language %q", name))
+				return
+			}
+			targets = append(targets, language)
+		}
+	}
+	if len(targets) == 0 {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("no target languages"))
+		return
+	}
+
+	logf("🔌 API translation to %d languages\n", len(targets))
+	source := detectSourceLanguage(config, req.Text)
+	response := apiTranslateResponse{SourceLanguage: source}
+	var codes, texts []string
+	for _, result := range translateFromSource(config, req.Text, source, targets) {
+		translation := apiTranslation{Language: result.Language.Code, Label: result.Language.Label, Text: result.Text}
+		if result.Err != nil {
+			logf("❌ %s translation error: %v\n", result.Language.Label, result.Err)
+			translation.Error = redact(result.Err.Error())
+		} else {
+			codes = append(codes, result.Language.Code)
+			texts = append(texts, result.Text)
+		}
+		response.Translations = append(response.Translations, translation)
+	}
+
+	if len(codes) == 0 {
+		writeAPIJSON(w, http.StatusBadGateway, response)
+		return
+	}
+	addHistory(historyEntry{Action: "api", SourceLanguage: source, Targets: codes, Text: req.Text, Result: strings.Join(texts, "\n")})
+	writeAPIJSON(w, http.StatusOK, response)
+}
+
+// GET /languages: all known languages, selected ones are marked
+func handleAPILanguages(w http.ResponseWriter, r *http.Request) {
+	config := currentConfig()
+	languages := []apiLanguage{}
+	for _, language := range allLanguages(config) {
+		languages = append(languages, apiLanguage{Language: language, Selected: contains(config.SelectedLanguages, language.Code)})
+	}
+	writeAPIJSON(w, http.StatusOK, languages)
+}
+
+// GET /models?provider=name: models of a provider, the configured one by default
+func handleAPIModels(w http.ResponseWriter, r *http.Request) {
+	config := currentConfig()
+	provider := r.URL.Query().Get("provider")
+	if provider == "" {
+		provider = config.Provider
+	}
+	if !contains(translationProviders, provider) {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown provider %q", provider))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]any{
+		"provider": provider,
+		"model":    config.Model,
+		"models":   modelsForProvider(provider),
+	})
+}
+
+// GET /history: recent translations, newest last
+func handleAPIHistory(w http.ResponseWriter, r *http.Request) {
+	entries := recentHistory()
+	if entries == nil {
+		entries = []historyEntry{}
+	}
+	writeAPIJSON(w, http.StatusOK, entries)
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logf("❌ Cannot write API response: %v\n", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, apiError{Error: redact(err.Error())})
+}
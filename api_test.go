@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Find a port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// Send GET /languages with a token and return the status code
+func getAPILanguages(t *testing.T, config Config, token string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "http://"+apiAddr(config)+"/languages", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAPIServerRestart(t *testing.T) {
+	config := Config{APIEnabled: true, APIPort: freePort(t), APIToken: "api-token-0123456789"}
+	setConfig(config)
+	applyAPIServer(config)
+	t.Cleanup(func() {
+		setConfig(Config{})
+		applyAPIServer(Config{})
+	})
+
+	if status := getAPILanguages(t, config, config.APIToken); status != http.StatusOK {
+		t.Errorf("status with token = %d", status)
+	}
+	if status := getAPILanguages(t, config, "wrong-token-0123456789"); status != http.StatusUnauthorized {
+		t.Errorf("status with wrong token = %d", status)
+	}
+
+	// Stopping returns right away and frees the port for the next start
+	start := time.Now()
+	applyAPIServer(Config{})
+	applyAPIServer(config)
+	if elapsed := time.Since(start); elapsed > apiShutdownTimeout/2 {
+		t.Errorf("restart took %v", elapsed)
+	}
+	if status := getAPILanguages(t, config, config.APIToken); status != http.StatusOK {
+		t.Errorf("status after restart = %d", status)
+	}
+}
+
+func TestAPITranslateRedactsErrors(t *testing.T) {
+	// A backend that echoes the key it was sent in its error body
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `{"error":"key %s is not allowed"}`, r.Header.Get("x-goog-api-key"))
+	}))
+	t.Cleanup(backend.Close)
+	config := Config{Provider: providerGemini, GeminiAPIKey: testSecretKey, GeminiBaseURL: backend.URL, SelectedLanguages: []string{"en", "ja"}}
+	setConfig(config)
+	setSecrets(config)
+	t.Cleanup(func() {
+		setConfig(Config{})
+		setSecrets(Config{})
+	})
+
+	recorder := httptest.NewRecorder()
+	handleAPITranslate(recorder, httptest.NewRequest(http.MethodPost, "/translate", strings.NewReader(`{"text":"Xin chào"}`)))
+	if recorder.Code != http.StatusBadGateway {
+		t.Errorf("status = %d", recorder.Code)
+	}
+	if body := recorder.Body.String(); strings.Contains(body, testSecretKey) || !strings.Contains(body, redactedText) {
+		t.Errorf("response does not hide the API key: %s", body)
+	}
+}
+
+func TestAPITranslateDetectsWithProfile(t *testing.T) {
+	var detectPath atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(data), "Identify the language") {
+			detectPath.Store(r.URL.Path)
+		}
+		io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"en"}]}}]}`)
+	}))
+	t.Cleanup(backend.Close)
+	setConfig(Config{
+		Provider: providerGemini, Model: "model-a", GeminiAPIKey: "gemini-key", GeminiBaseURL: backend.URL,
+		SelectedLanguages: []string{"en"}, ModelLanguageDetection: true,
+		Profiles: []Profile{{Name: "B", Model: "model-b", SelectedLanguages: []string{"ja"}}},
+	})
+	t.Cleanup(func() { setConfig(Config{}) })
+
+	// Text without letters can't be detected locally, so the model is asked
+	recorder := httptest.NewRecorder()
+	handleAPITranslate(recorder, httptest.NewRequest(http.MethodPost, "/translate", strings.NewReader(`{"text":"123 !!!","profile":"B"}`)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+	if got, want := detectPath.Load(), "/models/model-b:generateContent"; got != want {
+		t.Errorf("language detected with %v, want the model of the profile at %s", got, want)
+	}
+}
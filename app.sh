@@ -113,16 +113,8 @@ cat > dist/translator.app/Contents/Info.plist << 'PLIST'
 </plist>
 PLIST
 
-# Copy config files to app bundle (if they exist)
-if [ -f config.json ]; then
-    echo "📋 Copying config.json to app bundle..."
-    cp config.json dist/translator.app/Contents/Resources/
-fi
-
-if [ -f .env ]; then
-    echo "📋 Copying .env file to app bundle..."
-    cp .env dist/translator.app/Contents/Resources/
-fi
+# config.json and .env are not copied into the bundle, they can contain API keys
+# and the zipped app is shared. Keys are kept in the keychain instead.
 
 # Set permissions
 echo "🔐 Setting permissions..."
@@ -165,9 +157,7 @@ echo "           ├── MacOS/"
 echo "           │   ├── translator (binary)"
 echo "           │   └── launcher.sh (with UTF-8 support)"
 echo "           └── Resources/"
-echo "               ├── icon.icns"
-echo "               ├── config.json (if exists)"
-echo "               └── .env (if exists)"
+echo "               └── icon.icns"
 echo ""
 echo "🚀 To run the app:"
 echo "   open dist/translator.app"
@@ -183,6 +173,6 @@ echo "   2. Grant AppleScript permissions when prompted"
 echo "   3. Enter your Gemini API key in the app's text field"
 echo ""
 echo "🎨 App icon has been created from icon.png"
-echo "💾 Config will be saved to config.json in the app bundle"
+echo "💾 Config will be saved to ~/Library/Application Support/superkeyboard, API keys go to the keychain"
 echo "🌐 Unicode support enabled for Japanese text"
 echo "📦 All build artifacts are organized in the dist/ directory"
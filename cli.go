@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Exit codes of the command line interface
+const (
+	exitOK          = 0
+	exitFailure     = 1 // Translation or API request failed
+	exitUsage       = 2 // Unknown command or bad arguments
+	exitConfigError = 3 // Config is missing something the command needs
+)
+
+// Usage of the subcommands, printed for unknown commands
+const cliUsage = `Usage: superkeyboard [--config path] [command]
+
+Without a command the app window is opened.
+
+Commands:
+  translate [--to EN,JP] [--prefix] [--style name] [--profile name] [text]
+                            Translate text from the arguments or stdin, one language per line
+  config get [key]          Print the config, or one of its fields, as JSON
+  config set <key> <value>  Change a config field, value is JSON or a plain string
+  models list [--provider name]
+                            List the models of a provider
+  listen --headless         Run the hotkey listener without a window
+  show                      Show the window of the running instance, or open the app
+  profile <name>            Switch the profile of the running instance, or of the config
+  translate-file <path>     Translate a file into the selected languages, written as
+                            name.<code>.ext next to it, by the running instance if any
+`
+
+// Errors of subcommands that map to exit codes
+var (
+	errUsage       = errors.New("usage error")
+	errConfigError = errors.New("config error")
+)
+
+// Run the subcommand in args, handled is false when the window should be opened instead
+func runSubcommand(args []string) (code int, handled bool) {
+	if len(args) == 0 {
+		return exitOK, false
+	}
+
+	// Keep stdout for command output so it can be used in pipelines
+	logOutput = os.Stderr
+
+	var err error
+	switch args[0] {
+	case "translate":
+		err = cliTranslate(args[1:], os.Stdin, os.Stdout)
+	case "config":
+		err = cliConfig(args[1:], os.Stdout)
+	case "models":
+		err = cliModels(args[1:], os.Stdout)
+	case "listen":
+		var headless bool
+		headless, err = cliListen(args[1:])
+		if err == nil && !headless {
+			logOutput = os.Stdout
+			return exitOK, false
+		}
+	case instanceShow:
+		// Opening the window forwards to the running instance
+		logOutput = os.Stdout
+		return exitOK, false
+	case instanceProfile:
+		err = cliProfile(args[1:], os.Stdout)
+	case instanceTranslateFile:
+		err = cliTranslateFile(args[1:], os.Stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, cliUsage)
+		return exitOK, true
+	default:
+		err = fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+	}
+
+	switch {
+	case err == nil:
+		return exitOK, true
+	case errors.Is(err, flag.ErrHelp):
+		return exitUsage, true
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "superkeyboard: %v\n\n%s", err, cliUsage)
+		return exitUsage, true
+	case errors.Is(err, errConfigError):
+		fmt.Fprintf(os.Stderr, "superkeyboard: %v\n", err)
+		return exitConfigError, true
+	default:
+		fmt.Fprintf(os.Stderr, "superkeyboard: %v\n", err)
+		return exitFailure, true
+	}
+}
+
+// Parse the flags of a subcommand, errors are usage errors
+func parseCommandFlags(flags *flag.FlagSet, args []string) error {
+	flags.SetOutput(os.Stderr)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+// translate: translate stdin or the arguments with the same pipeline as the dual translation hotkey
+func cliTranslate(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("translate", flag.ContinueOnError)
+	to := flags.String("to", "", "comma separated language codes or labels (default: selected languages)")
+	prefix := flags.Bool("prefix", false, "prefix each translation with [LANG]: (default: include_prefix of the config or profile)")
+	style := flags.String("style", "", "prompt style: "+strings.Join(promptStyleNames(), ", "))
+	profile := flags.String("profile", "", "use the settings of a profile")
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+
+	config := loadConfig()
+	if *profile != "" {
+		if err := switchProfile(&config, *profile); err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+	}
+	if *style != "" {
+		if !contains(promptStyleNames(), *style) {
+			return fmt.Errorf("%w: unknown prompt style %q", errUsage, *style)
+		}
+		config.PromptStyle = *style
+	}
+	// Without --prefix the output looks like the one of the app
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "prefix" {
+			config.IncludePrefix = *prefix
+		}
+	})
+	if !hasUsableAPIKey(config) {
+		return fmt.Errorf("%w: an API key is required for %s, set it with: superkeyboard config set %s_api_key <key>", errConfigError, config.Provider, config.Provider)
+	}
+
+	targets := languagesForCodes(config, config.SelectedLanguages)
+	if *to != "" {
+		targets = nil
+		for _, name := range strings.Split(*to, ",") {
+			language, ok := lookupLanguage(config, strings.TrimSpace(name))
+			if !ok {
+				return fmt.Errorf("%w: unknown language %q", errUsage, name)
+			}
+			targets = append(targets, language)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("%w: no target languages, use --to or select languages in the app", errUsage)
+	}
+
+	// Text comes from the arguments, or from stdin for pipelines
+	text := strings.Join(flags.Args(), " ")
+	if text == "" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("%w: no text to translate", errUsage)
+	}
+
+	// Translators read the config from the store
+	setConfig(config)
+	source := detectSourceLanguage(config, text)
+
+	failed := 0
+	for _, result := range translateFromSource(config, text, source, targets) {
+		if result.Err != nil {
+			logf("❌ %s translation error: %v\n", result.Language.Label, result.Err)
+			failed++
+			continue
+		}
+		if config.IncludePrefix {
+			fmt.Fprintf(stdout, "[%s]: %s\n", result.Language.Label, result.Text)
+		} else {
+			fmt.Fprintln(stdout, result.Text)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d translations failed", failed, len(targets))
+	}
+	return nil
+}
+
+// config get/set: read and change config fields by their JSON names
+func cliConfig(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: config needs get or set", errUsage)
+	}
+
+	config := loadConfig()
+	fields, err := configFields(config)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		if len(args) > 2 {
+			return fmt.Errorf("%w: config get takes at most one key", errUsage)
+		}
+		// API keys and the API token stay out of the output, like in the log
+		for key := range secretFields(&config) {
+			if value, ok := fields[key].(string); ok && value != "" {
+				fields[key] = redactedText
+			}
+		}
+		if len(args) == 1 {
+			return printJSON(stdout, fields)
+		}
+		if !contains(configKeys(), args[1]) {
+			return fmt.Errorf("%w: unknown config key %q", errUsage, args[1])
+		}
+		value := fields[args[1]] // Empty optional fields are left out of the JSON
+		if value == nil {
+			return printJSON(stdout, nil)
+		}
+		if s, ok := value.(string); ok {
+			fmt.Fprintln(stdout, s)
+			return nil
+		}
+		return printJSON(stdout, value)
+
+	case "set":
+		if len(args) != 3 {
+			return fmt.Errorf("%w: config set needs a key and a value", errUsage)
+		}
+		key, raw := args[1], args[2]
+		if !contains(configKeys(), key) {
+			return fmt.Errorf("%w: unknown config key %q, known keys: %s", errUsage, key, strings.Join(configKeys(), ", "))
+		}
+		var value any
+		if json.Unmarshal([]byte(raw), &value) != nil {
+			value = raw // Plain strings don't need JSON quotes
+		}
+		// A string field takes the value as it is, e.g. a key made of digits, unless it is a quoted JSON string
+		if field, ok := configField(key); ok && field.Type.Kind() == reflect.String {
+			if _, quoted := value.(string); !quoted {
+				value = raw
+			}
+		}
+		fields[key] = value
+
+		updated, err := configFromFields(fields)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", errUsage, key, err)
+		}
+		if message := updated.Validate().For(key); message != "" {
+			return fmt.Errorf("%w: %s: %s", errConfigError, key, message)
+		}
+		setConfig(config)
+		return updateConfig(func(c *Config) { *c = updated })
+
+	default:
+		return fmt.Errorf("%w: unknown config command %q", errUsage, args[0])
+	}
+}
+
+// Get the fields of a config by their JSON names
+func configFields(config Config) (map[string]any, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+// Build a config from fields by their JSON names
+func configFromFields(fields map[string]any) (Config, error) {
+	var config Config
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// Get the JSON names of all config fields, sorted
+func configKeys() []string {
+	var keys []string
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(Config{})) {
+		if name := configFieldName(field); name != "" {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Find a config field by its JSON name
+func configField(key string) (reflect.StructField, bool) {
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(Config{})) {
+		if configFieldName(field) == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Get the JSON name of a config field, empty for fields that are not saved
+func configFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func printJSON(w io.Writer, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
+
+// models list: print the models of a provider, Gemini models are fetched live when possible
+func cliModels(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("%w: models needs list", errUsage)
+	}
+	flags := flag.NewFlagSet("models list", flag.ContinueOnError)
+	provider := flags.String("provider", "", "provider to list models of (default: configured provider)")
+	if err := parseCommandFlags(flags, args[1:]); err != nil {
+		return err
+	}
+
+	config := loadConfig()
+	setConfig(config)
+	if *provider == "" {
+		*provider = config.Provider
+	}
+	if !contains(translationProviders, *provider) {
+		return fmt.Errorf("%w: unknown provider %q", errUsage, *provider)
+	}
+
+	models := modelsForProvider(*provider)
+	if *provider == providerGemini && config.GeminiAPIKey != "" {
+		// The cached list is still printed when fetching fails
+		models, _ = refreshGeminiModels()
+	}
+	for _, model := range models {
+		fmt.Fprintln(stdout, model)
+	}
+	return nil
+}
+
+// profile: switch the profile of the running instance, the config file is changed
+// directly when the app isn't running
+func cliProfile(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: profile needs a profile name", errUsage)
+	}
+	message, err := sendToInstance(instanceProfile, args[0])
+	if !errors.Is(err, errNoInstance) {
+		if err == nil {
+			fmt.Fprintln(stdout, message)
+		}
+		return err
+	}
+
+	setConfig(loadConfig())
+	if err := activateProfile(args[0]); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	fmt.Fprintf(stdout, "switched to profile %s\n", currentConfig().ActiveProfile)
+	return nil
+}
+
+// translate-file: translate a file, by the running instance so its profile and settings are used
+func cliTranslateFile(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: translate-file needs a file path", errUsage)
+	}
+	// The running instance has its own working directory
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	message, err := sendToInstance(instanceTranslateFile, path)
+	if !errors.Is(err, errNoInstance) {
+		if message != "" {
+			fmt.Fprintln(stdout, message)
+		}
+		return err
+	}
+
+	config := loadConfig()
+	if !hasUsableAPIKey(config) {
+		return fmt.Errorf("%w: an API key is required for %s, set it with: superkeyboard config set %s_api_key <key>", errConfigError, config.Provider, config.Provider)
+	}
+	setConfig(config)
+	paths, err := translateFile(path)
+	for _, written := range paths {
+		fmt.Fprintln(stdout, written)
+	}
+	return err
+}
+
+// listen: run the hotkey listener, headless is false when the window should be opened
+func cliListen(args []string) (bool, error) {
+	flags := flag.NewFlagSet("listen", flag.ContinueOnError)
+	headless := flags.Bool("headless", false, "run without opening the window")
+	if err := parseCommandFlags(flags, args); err != nil {
+		return false, err
+	}
+	if !*headless {
+		return false, nil
+	}
+
+	return true, runHeadless()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Write a config with secrets to the test config path, keys go to the file credential store
+func writeTestConfig(t *testing.T, config Config) {
+	t.Helper()
+	config.CredentialStore = credentialStoreFile
+	if err := saveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(getConfigPath())
+		setConfig(Config{})
+	})
+}
+
+func TestCLIConfigGetRedactsSecrets(t *testing.T) {
+	secrets := []string{"gemini-secret-key", "deepl-secret-key", "api-token-0123456789"}
+	writeTestConfig(t, Config{
+		Provider:          providerGemini,
+		SelectedLanguages: []string{"en"},
+		GeminiAPIKey:      secrets[0],
+		DeepLAPIKey:       secrets[1],
+		APIToken:          secrets[2],
+	})
+
+	var output bytes.Buffer
+	if err := cliConfig([]string{"get"}, &output); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"api_token", "deepl_api_key"} {
+		if err := cliConfig([]string{"get", key}, &output); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, secret := range secrets {
+		if strings.Contains(output.String(), secret) {
+			t.Errorf("config get printed %q:\n%s", secret, output.String())
+		}
+	}
+	var fields map[string]any
+	if err := json.NewDecoder(&output).Decode(&fields); err != nil {
+		t.Fatal(err)
+	}
+	for key := range secretFields(&Config{}) {
+		if value, ok := fields[key]; ok && value != redactedText {
+			t.Errorf("%s = %v, want %s", key, value, redactedText)
+		}
+	}
+}
+
+func TestCLIConfigSet(t *testing.T) {
+	writeTestConfig(t, Config{Provider: providerGemini, SelectedLanguages: []string{"en"}})
+
+	tests := []struct {
+		key   string
+		value string
+		check func(config Config) bool
+	}{
+		// String fields keep values that would parse as another JSON type
+		{"deepl_api_key", "12345", func(c Config) bool { return c.DeepLAPIKey == "12345" }},
+		{"api_token", "true", func(c Config) bool { return c.APIToken == "true" }},
+		{"openai_base_url", `"http://localhost:8080/v1"`, func(c Config) bool { return c.OpenAIBaseURL == "http://localhost:8080/v1" }},
+		{"model", "gemini-2.5-flash", func(c Config) bool { return c.Model == "gemini-2.5-flash" }},
+		// Other fields are JSON
+		{"api_port", "9000", func(c Config) bool { return c.APIPort == 9000 }},
+		{"selected_languages", `["en","ja"]`, func(c Config) bool { return strings.Join(c.SelectedLanguages, ",") == "en,ja" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if err := cliConfig([]string{"set", tt.key, tt.value}, &bytes.Buffer{}); err != nil {
+				t.Fatal(err)
+			}
+			if config := loadConfig(); !tt.check(config) {
+				t.Errorf("config set %s %s was not applied", tt.key, tt.value)
+			}
+		})
+	}
+
+	if err := cliConfig([]string{"set", "api_port", "high"}, &bytes.Buffer{}); err == nil {
+		t.Error("config set api_port high succeeded")
+	}
+}
+
+func TestCLITranslatePrefix(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"Hello"}]}}]}`)
+	}))
+	t.Cleanup(backend.Close)
+	writeTestConfig(t, Config{
+		Provider: providerGemini, GeminiAPIKey: "gemini-key", GeminiBaseURL: backend.URL,
+		SelectedLanguages: []string{"en"}, IncludePrefix: true,
+	})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "[EN]: Hello\n"},
+		{[]string{"--prefix=false"}, "Hello\n"},
+		{[]string{"--prefix"}, "[EN]: Hello\n"},
+	}
+	for _, tt := range tests {
+		var output bytes.Buffer
+		if err := cliTranslate(append(tt.args, "Xin chào"), strings.NewReader(""), &output); err != nil {
+			t.Fatal(err)
+		}
+		if output.String() != tt.want {
+			t.Errorf("translate %v = %q, want %q", tt.args, output.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MIME type of plain text clipboard content
+const clipboardTextType = "text/plain"
+
+// How long to wait for the target app to read a paste before restoring the clipboard
+const clipboardRestoreDelay = 300 * time.Millisecond
+
+// Default time to wait for a copy to reach the clipboard
+const defaultCopyTimeout = 1000 * time.Millisecond
+
+// How often the clipboard is checked while waiting for a copy
+const clipboardPollInterval = 25 * time.Millisecond
+
+// Returned when the copy shortcut did not put anything on the clipboard
+var errNoSelection = errors.New("no selection")
+
+// ClipboardContent is a saved clipboard, either text or binary data of a single type
+type ClipboardContent struct {
+	Type string
+	Data []byte
+}
+
+// Save the clipboard as text
+func saveClipboardText(c Clipboard) (ClipboardContent, error) {
+	text, err := c.Read()
+	if err != nil {
+		return ClipboardContent{}, err
+	}
+	return ClipboardContent{Type: clipboardTextType, Data: []byte(text)}, nil
+}
+
+// Pick the type to save from the types offered by the clipboard, images win over text
+func pickClipboardType(types []string) string {
+	for _, t := range types {
+		if strings.HasPrefix(t, "image/") {
+			return t
+		}
+	}
+	return clipboardTextType
+}
+
+// Save the clipboard before an action overwrites it, nil if it cannot be read
+func backupClipboard() *ClipboardContent {
+	content, err := systemClipboard.Save()
+	if err != nil {
+		// An empty clipboard cannot be read by some backends, there is nothing to restore
+		logf("⚠️ Could not save clipboard, it will not be restored: %v\n", err)
+		return nil
+	}
+	return &content
+}
+
+// Put back the clipboard saved by backupClipboard once the paste has been handled
+func restoreClipboard(content *ClipboardContent) {
+	if content == nil {
+		return
+	}
+	time.Sleep(clipboardRestoreDelay)
+	if err := systemClipboard.Restore(*content); err != nil {
+		logf("❌ Error restoring clipboard: %v\n", err)
+		return
+	}
+	logf("📋 Original clipboard restored (%s)\n", content.Type)
+}
+
+// Get the copy timeout from config
+func copyTimeout(config Config) time.Duration {
+	if config.CopyTimeoutMs <= 0 {
+		return defaultCopyTimeout
+	}
+	return time.Duration(config.CopyTimeoutMs) * time.Millisecond
+}
+
+// Copy the selection and return it once it reaches the clipboard. A sentinel is put on the
+// clipboard first, so an unchanged clipboard means nothing was selected. The saved clipboard
+// is put back when nothing was copied.
+func copySelection(saved *ClipboardContent) (string, error) {
+	sentinel := fmt.Sprintf("superkeyboard-sentinel-%d", time.Now().UnixNano())
+	if err := systemClipboard.Write(sentinel); err != nil {
+		return "", err
+	}
+
+	if err := systemKeys.Copy(); err != nil {
+		putBackClipboard(saved)
+		return "", err
+	}
+
+	deadline := time.Now().Add(copyTimeout(currentConfig()))
+	for time.Now().Before(deadline) {
+		time.Sleep(clipboardPollInterval)
+		text, err := systemClipboard.Read()
+		if err != nil || text == sentinel {
+			continue // Copy has not finished yet
+		}
+		if text == "" {
+			break
+		}
+		return text, nil
+	}
+
+	putBackClipboard(saved)
+	return "", errNoSelection
+}
+
+// Put back a saved clipboard right away, used when nothing was pasted
+func putBackClipboard(content *ClipboardContent) {
+	if content == nil {
+		return
+	}
+	if err := systemClipboard.Restore(*content); err != nil {
+		logf("❌ Error restoring clipboard: %v\n", err)
+	}
+}
+
+// MIME types of the macOS image clipboard classes
+var macImageClasses = map[string]string{
+	"PNGf": "image/png",
+	"TIFF": "image/tiff",
+}
+
+func (m macClipboard) Save() (ClipboardContent, error) {
+	info, err := runCommand("osascript", "-e", "clipboard info")
+	if err != nil {
+		return ClipboardContent{}, err
+	}
+
+	for _, class := range []string{"PNGf", "TIFF"} {
+		if !strings.Contains(info, "«class "+class+"»") {
+			continue
+		}
+		// AppleScript prints binary data as «data PNGf89504E47...»
+		output, err := runCommand("osascript", "-e", fmt.Sprintf("the clipboard as «class %s»", class))
+		if err != nil {
+			return ClipboardContent{}, err
+		}
+		encoded := strings.TrimSpace(output)
+		encoded = strings.TrimPrefix(encoded, "«data "+class)
+		encoded = strings.TrimSuffix(encoded, "»")
+		data, err := hex.DecodeString(encoded)
+		if err != nil {
+			return ClipboardContent{}, fmt.Errorf("cannot decode clipboard image: %v", err)
+		}
+		return ClipboardContent{Type: macImageClasses[class], Data: data}, nil
+	}
+
+	return saveClipboardText(m)
+}
+
+func (m macClipboard) Restore(content ClipboardContent) error {
+	for class, mimeType := range macImageClasses {
+		if content.Type != mimeType {
+			continue
+		}
+		// Pass the script on stdin, images are too large for an argument
+		script := fmt.Sprintf("set the clipboard to «data %s%s»", class, strings.ToUpper(hex.EncodeToString(content.Data)))
+		return runWithInput(script, "osascript", "-")
+	}
+	return m.Write(string(content.Data))
+}
+
+func (x xclipClipboard) Save() (ClipboardContent, error) {
+	targets, err := runCommand("xclip", "-selection", "clipboard", "-target", "TARGETS", "-out")
+	if err != nil {
+		return ClipboardContent{}, err
+	}
+
+	target := pickClipboardType(strings.Fields(targets))
+	if target == clipboardTextType {
+		return saveClipboardText(x)
+	}
+	data, err := runCommand("xclip", "-selection", "clipboard", "-target", target, "-out")
+	if err != nil {
+		return ClipboardContent{}, err
+	}
+	return ClipboardContent{Type: target, Data: []byte(data)}, nil
+}
+
+func (x xclipClipboard) Restore(content ClipboardContent) error {
+	if content.Type == clipboardTextType {
+		return x.Write(string(content.Data))
+	}
+	return runWithInput(string(content.Data), "xclip", "-selection", "clipboard", "-target", content.Type, "-in")
+}
+
+// xsel only handles text
+func (x xselClipboard) Save() (ClipboardContent, error) {
+	return saveClipboardText(x)
+}
+
+func (x xselClipboard) Restore(content ClipboardContent) error {
+	return x.Write(string(content.Data))
+}
+
+func (w waylandClipboard) Save() (ClipboardContent, error) {
+	types, err := runCommand("wl-paste", "--list-types")
+	if err != nil {
+		return ClipboardContent{}, err
+	}
+
+	mimeType := pickClipboardType(strings.Fields(types))
+	if mimeType == clipboardTextType {
+		return saveClipboardText(w)
+	}
+	data, err := runCommand("wl-paste", "--type", mimeType)
+	if err != nil {
+		return ClipboardContent{}, err
+	}
+	return ClipboardContent{Type: mimeType, Data: []byte(data)}, nil
+}
+
+func (w waylandClipboard) Restore(content ClipboardContent) error {
+	if content.Type == clipboardTextType {
+		return w.Write(string(content.Data))
+	}
+	return runWithInput(string(content.Data), "wl-copy", "--type", content.Type)
+}
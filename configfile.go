@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Version of the config file format written by this build
+const configVersion = 2
+
+// configMigration upgrades the raw JSON of a config file to the next format version
+type configMigration struct {
+	version int // Version the file has after the migration
+	name    string
+	migrate func(raw map[string]any)
+}
+
+// Migrations in the order they are applied, append new ones at the end
+var configMigrations = []configMigration{
+	{version: 1, name: "BCP-47 language codes", migrate: migrateLegacyLanguageCodes},
+	{version: 2, name: "clipboard_language", migrate: migrateClipboardLanguage},
+}
+
+// configParseError is returned when config.json exists but cannot be used
+type configParseError struct {
+	Path string
+	Err  error
+}
+
+func (e *configParseError) Error() string {
+	return fmt.Sprintf("cannot read %s: %v", e.Path, e.Err)
+}
+
+func (e *configParseError) Unwrap() error { return e.Err }
+
+// Version 1: EN/VN/JP of older configs to BCP-47 codes
+func migrateLegacyLanguageCodes(raw map[string]any) {
+	if codes, ok := raw["selected_languages"].([]any); ok {
+		for i, code := range codes {
+			if s, ok := code.(string); ok {
+				codes[i] = normalizeLanguageCode(s)
+			}
+		}
+	}
+	if code, ok := raw["g_language"].(string); ok {
+		raw["g_language"] = normalizeLanguageCode(code)
+	}
+}
+
+// Version 2: g_language was named after the default hotkey, it is the language of the clipboard translation
+func migrateClipboardLanguage(raw map[string]any) {
+	if code, ok := raw["g_language"]; ok {
+		raw["clipboard_language"] = code
+		delete(raw, "g_language")
+	}
+}
+
+// Parse config.json and upgrade it to the current version, migrated is true when a migration ran
+func parseConfigData(data []byte) (config Config, migrated bool, err error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return config, false, err
+	}
+	if raw == nil {
+		return config, false, fmt.Errorf("config is not a JSON object")
+	}
+
+	version := 0
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > configVersion {
+		return config, false, fmt.Errorf("config version %d is newer than this app supports (%d)", version, configVersion)
+	}
+
+	for _, migration := range configMigrations {
+		if migration.version <= version {
+			continue
+		}
+		logf("🔧 Migrating config to version %d: %s\n", migration.version, migration.name)
+		migration.migrate(raw)
+		migrated = true
+	}
+	raw["version"] = configVersion
+
+	if data, err = json.Marshal(raw); err != nil {
+		return config, false, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, false, err
+	}
+	return config, migrated, nil
+}
+
+// Write a file through a temporary file in the same directory and rename it into place,
+// so a crash never leaves a half-written file behind
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // Fails once the file has been renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Path of the copy of the last config that was read or written successfully
+func configBackupPath(configPath string) string {
+	return configPath + ".bak"
+}
+
+// Keep a copy of a good config to recover from a broken file
+func backupConfigFile(configPath string, data []byte) {
+	backupPath := configBackupPath(configPath)
+	if old, err := os.ReadFile(backupPath); err == nil && string(old) == string(data) {
+		return
+	}
+	if err := writeFileAtomic(backupPath, data, 0600); err != nil {
+		logf("⚠️ Cannot back up config: %v\n", err)
+	}
+}
+
+// Move a config file that cannot be parsed out of the way and put the backup in its place.
+// The returned error describes what happened for the user.
+func recoverConfigFile(configPath string, parseErr error) error {
+	brokenPath := configPath + ".broken"
+	if err := os.Rename(configPath, brokenPath); err != nil {
+		return fmt.Errorf("%v\n\nThe file could not be moved aside (%v), fix or delete it and restart the app", parseErr, err)
+	}
+	logf("⚠️ Moved unreadable config to: %s\n", brokenPath)
+
+	data, err := os.ReadFile(configBackupPath(configPath))
+	if err == nil {
+		if _, _, err = parseConfigData(data); err == nil {
+			err = writeFileAtomic(configPath, data, 0600)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%v\n\nIt was moved to %s and default settings are used", parseErr, brokenPath)
+	}
+	logln("✅ Restored config from backup")
+	return fmt.Errorf("%v\n\nIt was moved to %s and the last good config was restored", parseErr, brokenPath)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Wait for an editor to finish writing before reloading the config file
+const configReloadDelay = 200 * time.Millisecond
+
+// configStore holds the config shared by the UI and the hotkey goroutines
+type configStore struct {
+	mu          sync.RWMutex
+	config      Config
+	subscribers []func(Config)
+
+	// Problems with the config file and who to tell about them
+	lastError        error
+	errorSubscribers []func(error)
+
+	// Serializes updates so saves are written in the order they were made
+	updateMu sync.Mutex
+
+	// Content of the last write, file events for it are our own and not reloaded
+	writtenMu sync.Mutex
+	written   []byte
+}
+
+// Config of the running app
+var configs configStore
+
+// Get a copy of the current config that is safe to read and change
+func currentConfig() Config {
+	configs.mu.RLock()
+	defer configs.mu.RUnlock()
+	return cloneConfig(configs.config)
+}
+
+// Change the config, save it and notify subscribers. Nothing is saved when the change
+// leaves the config as it was.
+func updateConfig(change func(config *Config)) error {
+	configs.updateMu.Lock()
+	defer configs.updateMu.Unlock()
+
+	configs.mu.Lock()
+	updated := cloneConfig(configs.config)
+	change(&updated)
+	// A profile switched to in this update already has its settings, syncing would copy the
+	// settings of the previous profile into the fields it leaves empty
+	if updated.ActiveProfile == configs.config.ActiveProfile {
+		syncActiveProfile(&updated)
+	}
+	if reflect.DeepEqual(updated, configs.config) {
+		configs.mu.Unlock()
+		return nil
+	}
+	configs.config = updated
+	configs.mu.Unlock()
+
+	err := saveConfig(updated)
+	notifyConfig(updated)
+	return err
+}
+
+// Replace the config without saving it, used at startup and when the file changed
+func setConfig(config Config) {
+	configs.updateMu.Lock()
+	defer configs.updateMu.Unlock()
+	replaceConfig(config)
+}
+
+// Replace the config and notify subscribers, the caller holds updateMu
+func replaceConfig(config Config) {
+	configs.mu.Lock()
+	configs.config = cloneConfig(config)
+	configs.mu.Unlock()
+	notifyConfig(config)
+}
+
+// Call fn with the new config after every change, fn runs on the goroutine that made the change
+func subscribeConfig(fn func(config Config)) {
+	configs.mu.Lock()
+	defer configs.mu.Unlock()
+	configs.subscribers = append(configs.subscribers, fn)
+}
+
+func notifyConfig(config Config) {
+	configs.mu.RLock()
+	subscribers := slices.Clone(configs.subscribers)
+	configs.mu.RUnlock()
+	for _, fn := range subscribers {
+		fn(cloneConfig(config))
+	}
+}
+
+// Tell the user about a config file that could not be read
+func reportConfigError(err error) {
+	configs.mu.Lock()
+	configs.lastError = err
+	subscribers := slices.Clone(configs.errorSubscribers)
+	configs.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(err)
+	}
+}
+
+// Call fn with every config file error, an error reported before subscribing is passed right away
+func subscribeConfigErrors(fn func(err error)) {
+	configs.mu.Lock()
+	configs.errorSubscribers = append(configs.errorSubscribers, fn)
+	err := configs.lastError
+	configs.mu.Unlock()
+	if err != nil {
+		fn(err)
+	}
+}
+
+// Remember what saveConfig wrote so the file watcher can ignore it
+func noteConfigWritten(data []byte) {
+	configs.writtenMu.Lock()
+	defer configs.writtenMu.Unlock()
+	configs.written = data
+}
+
+func isOwnConfigWrite(data []byte) bool {
+	configs.writtenMu.Lock()
+	defer configs.writtenMu.Unlock()
+	return bytes.Equal(configs.written, data)
+}
+
+// Copy a config so slices and maps are not shared with the store
+func cloneConfig(config Config) Config {
+	config.SelectedLanguages = slices.Clone(config.SelectedLanguages)
+	config.Languages = slices.Clone(config.Languages)
+	config.CustomLanguages = slices.Clone(config.CustomLanguages)
+	config.GeminiModels = slices.Clone(config.GeminiModels)
+	config.Hotkeys = maps.Clone(config.Hotkeys)
+	config.CredentialRefs = maps.Clone(config.CredentialRefs)
+	config.Profiles = slices.Clone(config.Profiles)
+	for i, profile := range config.Profiles {
+		config.Profiles[i].SelectedLanguages = slices.Clone(profile.SelectedLanguages)
+		config.Profiles[i].Hotkeys = maps.Clone(profile.Hotkeys)
+	}
+	config.Actions = slices.Clone(config.Actions)
+	for i, action := range config.Actions {
+		config.Actions[i].Targets = slices.Clone(action.Targets)
+	}
+	return config
+}
+
+// Watch the config file and apply changes made outside the app, e.g. in a text editor.
+// The directory is watched because editors often replace the file instead of writing it.
+func watchConfigFile() {
+	configPath := getConfigPath()
+	dir := filepath.Dir(configPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logf("⚠️ Config hot reload disabled: %v\n", err)
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logf("⚠️ Config hot reload disabled: %v\n", err)
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		logf("⚠️ Config hot reload disabled: %v\n", err)
+		return
+	}
+	logf("👀 Watching %s for changes\n", configPath)
+
+	var reloadTimer *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(configPath) || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				continue
+			}
+			// Editors write in several steps, reload once they are done
+			if reloadTimer != nil {
+				reloadTimer.Stop()
+			}
+			reloadTimer = time.AfterFunc(configReloadDelay, reloadConfigFile)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logf("⚠️ Config watcher error: %v\n", err)
+		}
+	}
+}
+
+// Reload the config file after it changed on disk, invalid files are ignored until fixed
+func reloadConfigFile() {
+	configPath := getConfigPath()
+	data, err := os.ReadFile(configPath)
+	if err != nil || isOwnConfigWrite(data) {
+		return
+	}
+
+	// Reading saves a migrated file, which must not interleave with the saves of updateConfig
+	configs.updateMu.Lock()
+	defer configs.updateMu.Unlock()
+
+	config, err := readConfigFile(configPath)
+	if err != nil {
+		logf("⚠️ Not reloading config, %s is invalid: %v\n", configPath, err)
+		reportConfigError(fmt.Errorf("%v\n\nThe app keeps using the previous settings until the file is fixed", err))
+		return
+	}
+	setSecrets(config)
+	replaceConfig(config)
+	logf("🔄 Reloaded config from: %s\n", configPath)
+}
@@ -0,0 +1,627 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Service name the API keys are stored under
+const credentialService = "superkeyboard"
+
+// Credential backends
+const (
+	credentialStoreKeychain      = "keychain"
+	credentialStoreSecretService = "secret-service"
+	credentialStoreFile          = "file"
+)
+
+// Returned when the store has no secret for an account
+var errCredentialNotFound = errors.New("credential not found")
+
+// CredentialStore keeps API keys outside of config.json, one secret per account
+type CredentialStore interface {
+	Name() string
+	Get(account string) (string, error)
+	Set(account string, secret string) error
+	Delete(account string) error
+}
+
+// Opened stores by name, the Secret Service store keeps a D-Bus connection
+var (
+	credentialStoresMu sync.Mutex
+	credentialStores   = map[string]CredentialStore{}
+)
+
+// Get a credential store by name, opening it on first use
+func credentialStoreByName(name string) (CredentialStore, error) {
+	credentialStoresMu.Lock()
+	defer credentialStoresMu.Unlock()
+	if store, ok := credentialStores[name]; ok {
+		return store, nil
+	}
+
+	var store CredentialStore
+	switch name {
+	case credentialStoreKeychain:
+		if !hasCommand("security") {
+			return nil, fmt.Errorf("macOS keychain is not available")
+		}
+		store = keychainStore{}
+	case credentialStoreSecretService:
+		s, err := newSecretServiceStore()
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	case credentialStoreFile:
+		store = &fileCredentialStore{dir: filepath.Dir(getConfigPath())}
+	default:
+		return nil, fmt.Errorf("unknown credential store: %s", name)
+	}
+
+	credentialStores[name] = store
+	return store, nil
+}
+
+// Get the store for new secrets: the one set in config, or the best one for the OS
+func defaultCredentialStore(config Config) CredentialStore {
+	var candidates []string
+	if config.CredentialStore != "" {
+		candidates = append(candidates, config.CredentialStore)
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = append(candidates, credentialStoreKeychain)
+	case "linux":
+		candidates = append(candidates, credentialStoreSecretService)
+	}
+	candidates = append(candidates, credentialStoreFile)
+
+	for _, name := range candidates {
+		store, err := credentialStoreByName(name)
+		if err == nil {
+			return store
+		}
+		logf("⚠️ Credential store %s not available: %v\n", name, err)
+	}
+	return nil
+}
+
+// Account of the local API token in the credential store
+const credentialAPIToken = "api_token"
+
+// Fields of a config kept in the credential store by account name: API keys and the local API token
+func credentialFields(config *Config) map[string]*string {
+	return map[string]*string{
+		providerGemini:         &config.GeminiAPIKey,
+		providerOpenAI:         &config.OpenAIAPIKey,
+		providerDeepL:          &config.DeepLAPIKey,
+		providerLibreTranslate: &config.LibreTranslateAPIKey,
+		credentialAPIToken:     &config.APIToken,
+	}
+}
+
+// Describe the secret of an account for messages, e.g. "gemini API key"
+func credentialLabel(account string) string {
+	if account == credentialAPIToken {
+		return "local API token"
+	}
+	return account + " API key"
+}
+
+// Split a reference like "keychain:gemini" into store name and account
+func parseCredentialRef(ref string) (string, string, error) {
+	store, account, ok := strings.Cut(ref, ":")
+	if !ok || store == "" || account == "" {
+		return "", "", fmt.Errorf("invalid credential reference: %s", ref)
+	}
+	return store, account, nil
+}
+
+// Secrets last written to or read from the stores by reference, so unchanged keys are not written
+// again. Only keys in here can be deleted, an empty field of a key that failed to load is kept.
+var (
+	knownCredentialsMu sync.Mutex
+	knownCredentials   = map[string]string{}
+)
+
+// Fill in the API keys and token of a config from the credential store, moving plaintext secrets of
+// older configs there. Returns true if a secret was moved and the config file should be written without it.
+func loadCredentials(config *Config) bool {
+	migrate := false
+	var target CredentialStore
+	for account, field := range credentialFields(config) {
+		if *field != "" {
+			// Without a store that takes it the secret stays in the file, so there is nothing to rewrite
+			if target == nil {
+				target = defaultCredentialStore(*config)
+			}
+			if target == nil {
+				logf("⚠️ No credential store available, keeping %s in config file\n", credentialLabel(account))
+				continue
+			}
+			ref, err := putCredential(target, account, *field)
+			if err != nil {
+				logf("⚠️ Cannot move %s to %s, keeping it in config file: %v\n", credentialLabel(account), target.Name(), err)
+				continue
+			}
+			if config.CredentialRefs == nil {
+				config.CredentialRefs = map[string]string{}
+			}
+			config.CredentialRefs[account] = ref
+			migrate = true
+			continue
+		}
+		ref, ok := config.CredentialRefs[account]
+		if !ok {
+			continue
+		}
+
+		storeName, storeAccount, err := parseCredentialRef(ref)
+		if err != nil {
+			logf("❌ %v\n", err)
+			continue
+		}
+		store, err := credentialStoreByName(storeName)
+		if err != nil {
+			logf("❌ Cannot open credential store for %s: %v\n", credentialLabel(account), err)
+			continue
+		}
+		secret, err := store.Get(storeAccount)
+		if err != nil {
+			logf("❌ Cannot read %s from %s: %v\n", credentialLabel(account), store.Name(), err)
+			continue
+		}
+		*field = secret
+
+		knownCredentialsMu.Lock()
+		knownCredentials[ref] = secret
+		knownCredentialsMu.Unlock()
+	}
+	return migrate
+}
+
+// Move the API keys of a config into the credential store and return the config to write to disk,
+// which only has references to the keys. Keys stay in the file if no store can take them.
+func storeCredentials(config Config) Config {
+	refs := map[string]string{}
+	for account, ref := range config.CredentialRefs {
+		refs[account] = ref
+	}
+
+	var store CredentialStore
+	for account, field := range credentialFields(&config) {
+		ref, hasRef := refs[account]
+
+		if *field == "" {
+			// Key was removed, drop it from the store too
+			if hasRef && deleteCredential(account, ref) {
+				delete(refs, account)
+			}
+			continue
+		}
+
+		knownCredentialsMu.Lock()
+		unchanged := hasRef && knownCredentials[ref] == *field
+		knownCredentialsMu.Unlock()
+		if unchanged {
+			*field = ""
+			continue
+		}
+
+		if store == nil {
+			if store = defaultCredentialStore(config); store == nil {
+				logf("⚠️ No credential store available, keeping %s in config file\n", credentialLabel(account))
+				continue
+			}
+		}
+		ref, err := putCredential(store, account, *field)
+		if err != nil {
+			logf("⚠️ Cannot save %s to %s, keeping it in config file: %v\n", credentialLabel(account), store.Name(), err)
+			continue
+		}
+		refs[account] = ref
+		*field = ""
+	}
+
+	config.CredentialRefs = refs
+	return config
+}
+
+// Save the secret of an account in a store and return the reference to it
+func putCredential(store CredentialStore, account string, secret string) (string, error) {
+	if err := store.Set(account, secret); err != nil {
+		return "", err
+	}
+	ref := store.Name() + ":" + account
+	knownCredentialsMu.Lock()
+	knownCredentials[ref] = secret
+	knownCredentialsMu.Unlock()
+	return ref, nil
+}
+
+// Delete the key of an account the user cleared. Returns false when the reference has to stay:
+// a key that could not be loaded (keyring locked, prompt dismissed, store not running yet) leaves
+// the field empty without being removed, and a store that cannot be reached still has the key.
+func deleteCredential(account string, ref string) bool {
+	knownCredentialsMu.Lock()
+	_, loaded := knownCredentials[ref]
+	knownCredentialsMu.Unlock()
+	if !loaded {
+		return false
+	}
+
+	storeName, storeAccount, err := parseCredentialRef(ref)
+	if err != nil {
+		return false
+	}
+	store, err := credentialStoreByName(storeName)
+	if err != nil {
+		logf("⚠️ Cannot delete %s, store not available: %v\n", credentialLabel(account), err)
+		return false
+	}
+	if err := store.Delete(storeAccount); err != nil && !errors.Is(err, errCredentialNotFound) {
+		logf("⚠️ Cannot delete %s from %s: %v\n", credentialLabel(account), store.Name(), err)
+		return false
+	}
+
+	knownCredentialsMu.Lock()
+	delete(knownCredentials, ref)
+	knownCredentialsMu.Unlock()
+	return true
+}
+
+// keychainStore uses the macOS login keychain through the security tool
+type keychainStore struct{}
+
+func (keychainStore) Name() string { return credentialStoreKeychain }
+
+func (keychainStore) Get(account string) (string, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", credentialService, "-a", account, "-w")
+	output, err := cmd.Output()
+	if err != nil {
+		// Exit code 44 means the item does not exist
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return "", errCredentialNotFound
+		}
+		return "", fmt.Errorf("security: %v", err)
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+func (keychainStore) Set(account string, secret string) error {
+	// Run the command in interactive mode so the key is not visible in the process list
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		credentialService, account, quoteSecurityArg(secret))
+	return runWithInput(command, "security", "-i")
+}
+
+func (keychainStore) Delete(account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", credentialService, "-a", account).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return errCredentialNotFound
+	}
+	return err
+}
+
+// Quote an argument for the command line of security -i
+func quoteSecurityArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// D-Bus names of the freedesktop Secret Service API
+const (
+	secretServiceName      = "org.freedesktop.secrets"
+	secretServicePath      = "/org/freedesktop/secrets"
+	secretCollectionPath   = "/org/freedesktop/secrets/aliases/default"
+	secretServiceIface     = "org.freedesktop.Secret.Service"
+	secretItemIface        = "org.freedesktop.Secret.Item"
+	secretPromptIface      = "org.freedesktop.Secret.Prompt"
+	secretPromptTimeout    = 2 * time.Minute
+	secretNoPrompt         = dbus.ObjectPath("/")
+	secretAttributeApp     = "application"
+	secretAttributeAccount = "account"
+)
+
+// Secret struct of the Secret Service API
+type dbusSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// secretServiceStore uses the Secret Service API over D-Bus (GNOME Keyring, KWallet, KeePassXC)
+type secretServiceStore struct {
+	conn *dbus.Conn
+}
+
+// Connect to the session bus and check that a Secret Service is running
+func newSecretServiceStore() (*secretServiceStore, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to session bus: %v", err)
+	}
+	s := &secretServiceStore{conn: conn}
+	session, err := s.openSession()
+	if err != nil {
+		return nil, fmt.Errorf("secret service not available: %v", err)
+	}
+	s.closeSession(session)
+	return s, nil
+}
+
+func (s *secretServiceStore) Name() string { return credentialStoreSecretService }
+
+func (s *secretServiceStore) service() dbus.BusObject {
+	return s.conn.Object(secretServiceName, secretServicePath)
+}
+
+// Open a session with plain transfer, the bus connection is local
+func (s *secretServiceStore) openSession() (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err := s.service().Call(secretServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session)
+	return session, err
+}
+
+func (s *secretServiceStore) closeSession(session dbus.ObjectPath) {
+	s.conn.Object(secretServiceName, session).Call("org.freedesktop.Secret.Session.Close", 0)
+}
+
+func (s *secretServiceStore) attributes(account string) map[string]string {
+	return map[string]string{secretAttributeApp: credentialService, secretAttributeAccount: account}
+}
+
+// Wait for the user to answer a prompt, e.g. to unlock the keyring
+func (s *secretServiceStore) waitForPrompt(prompt dbus.ObjectPath) error {
+	if prompt == secretNoPrompt || prompt == "" {
+		return nil
+	}
+
+	if err := s.conn.AddMatchSignal(dbus.WithMatchObjectPath(prompt), dbus.WithMatchInterface(secretPromptIface)); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(dbus.WithMatchObjectPath(prompt), dbus.WithMatchInterface(secretPromptIface))
+
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(secretServiceName, prompt).Call(secretPromptIface+".Prompt", 0, "").Err; err != nil {
+		return err
+	}
+
+	timeout := time.After(secretPromptTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != prompt || signal.Name != secretPromptIface+".Completed" {
+				continue
+			}
+			if len(signal.Body) > 0 {
+				if dismissed, ok := signal.Body[0].(bool); ok && dismissed {
+					return fmt.Errorf("prompt was dismissed")
+				}
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for keyring prompt")
+		}
+	}
+}
+
+// Find the item of an account, unlocking it if needed
+func (s *secretServiceStore) findItem(account string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := s.service().Call(secretServiceIface+".SearchItems", 0, s.attributes(account)).Store(&unlocked, &locked); err != nil {
+		return "", err
+	}
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) == 0 {
+		return "", errCredentialNotFound
+	}
+
+	var prompt dbus.ObjectPath
+	if err := s.service().Call(secretServiceIface+".Unlock", 0, locked[:1]).Store(&unlocked, &prompt); err != nil {
+		return "", err
+	}
+	if err := s.waitForPrompt(prompt); err != nil {
+		return "", err
+	}
+	return locked[0], nil
+}
+
+func (s *secretServiceStore) Get(account string) (string, error) {
+	item, err := s.findItem(account)
+	if err != nil {
+		return "", err
+	}
+	session, err := s.openSession()
+	if err != nil {
+		return "", err
+	}
+	defer s.closeSession(session)
+
+	var secret dbusSecret
+	if err := s.conn.Object(secretServiceName, item).Call(secretItemIface+".GetSecret", 0, session).Store(&secret); err != nil {
+		return "", err
+	}
+	return string(secret.Value), nil
+}
+
+func (s *secretServiceStore) Set(account string, secret string) error {
+	session, err := s.openSession()
+	if err != nil {
+		return err
+	}
+	defer s.closeSession(session)
+
+	properties := map[string]dbus.Variant{
+		secretItemIface + ".Label":      dbus.MakeVariant(fmt.Sprintf("%s %s", credentialService, credentialLabel(account))),
+		secretItemIface + ".Attributes": dbus.MakeVariant(s.attributes(account)),
+	}
+	value := dbusSecret{Session: session, Parameters: []byte{}, Value: []byte(secret), ContentType: "text/plain"}
+
+	var item, prompt dbus.ObjectPath
+	collection := s.conn.Object(secretServiceName, secretCollectionPath)
+	if err := collection.Call("org.freedesktop.Secret.Collection.CreateItem", 0, properties, value, true).Store(&item, &prompt); err != nil {
+		return err
+	}
+	return s.waitForPrompt(prompt)
+}
+
+func (s *secretServiceStore) Delete(account string) error {
+	item, err := s.findItem(account)
+	if err != nil {
+		return err
+	}
+	var prompt dbus.ObjectPath
+	if err := s.conn.Object(secretServiceName, item).Call(secretItemIface+".Delete", 0).Store(&prompt); err != nil {
+		return err
+	}
+	return s.waitForPrompt(prompt)
+}
+
+// fileCredentialStore keeps AES-GCM encrypted secrets in credentials.json, with the key in
+// credentials.key next to it. Both files are only readable by the user. This is obfuscation, not
+// protection: it keeps keys out of config.json, backups of it and app bundles, but anyone who can
+// read the config directory can decrypt them. Use keychain or secret-service where available.
+type fileCredentialStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func (f *fileCredentialStore) Name() string { return credentialStoreFile }
+
+func (f *fileCredentialStore) keyPath() string  { return filepath.Join(f.dir, "credentials.key") }
+func (f *fileCredentialStore) dataPath() string { return filepath.Join(f.dir, "credentials.json") }
+
+// Get the AES-GCM cipher, creating a random key on first use
+func (f *fileCredentialStore) cipher() (cipher.AEAD, error) {
+	key, err := os.ReadFile(f.keyPath())
+	if os.IsNotExist(err) {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(f.keyPath(), key, 0600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (f *fileCredentialStore) load() (map[string]string, error) {
+	entries := map[string]string{}
+	data, err := os.ReadFile(f.dataPath())
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (f *fileCredentialStore) save(entries map[string]string) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.dataPath(), data, 0600)
+}
+
+func (f *fileCredentialStore) Get(account string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	encoded, ok := entries[account]
+	if !ok {
+		return "", errCredentialNotFound
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := f.cipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("corrupted credential for %s", account)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(account))
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt credential for %s: %v", account, err)
+	}
+	return string(plain), nil
+}
+
+func (f *fileCredentialStore) Set(account string, secret string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := f.load()
+	if err != nil {
+		return err
+	}
+	aead, err := f.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	entries[account] = base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(secret), []byte(account)))
+	return f.save(entries)
+}
+
+func (f *fileCredentialStore) Delete(account string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[account]; !ok {
+		return errCredentialNotFound
+	}
+	delete(entries, account)
+	return f.save(entries)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+// memoryStore is a credential store for tests, failing makes Get fail like a locked keyring
+// and readOnly makes Set fail
+type memoryStore struct {
+	secrets  map[string]string
+	failing  bool
+	readOnly bool
+	deleted  []string
+}
+
+func (m *memoryStore) Name() string { return "memory" }
+
+func (m *memoryStore) Get(account string) (string, error) {
+	if m.failing {
+		return "", errors.New("keyring is locked")
+	}
+	secret, ok := m.secrets[account]
+	if !ok {
+		return "", errCredentialNotFound
+	}
+	return secret, nil
+}
+
+func (m *memoryStore) Set(account string, secret string) error {
+	if m.readOnly {
+		return errors.New("store is read-only")
+	}
+	m.secrets[account] = secret
+	return nil
+}
+
+func (m *memoryStore) Delete(account string) error {
+	m.deleted = append(m.deleted, account)
+	delete(m.secrets, account)
+	return nil
+}
+
+// Register a memory store with a Gemini key and forget keys loaded by other tests
+func useMemoryStore(t *testing.T) *memoryStore {
+	t.Helper()
+	store := &memoryStore{secrets: map[string]string{"gemini": "gemini-secret"}}
+	credentialStoresMu.Lock()
+	credentialStores["memory"] = store
+	credentialStoresMu.Unlock()
+	knownCredentialsMu.Lock()
+	clear(knownCredentials)
+	knownCredentialsMu.Unlock()
+	t.Cleanup(func() {
+		credentialStoresMu.Lock()
+		delete(credentialStores, "memory")
+		credentialStoresMu.Unlock()
+	})
+	return store
+}
+
+func TestStoreCredentialsDeletesClearedKey(t *testing.T) {
+	store := useMemoryStore(t)
+	config := Config{CredentialRefs: map[string]string{providerGemini: "memory:gemini"}}
+	loadCredentials(&config)
+	if config.GeminiAPIKey != "gemini-secret" {
+		t.Fatalf("GeminiAPIKey = %q", config.GeminiAPIKey)
+	}
+
+	config.GeminiAPIKey = ""
+	saved := storeCredentials(config)
+	if _, ok := saved.CredentialRefs[providerGemini]; ok {
+		t.Errorf("reference of the cleared key was kept: %v", saved.CredentialRefs)
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != "gemini" {
+		t.Errorf("deleted = %v, want [gemini]", store.deleted)
+	}
+}
+
+func TestStoreCredentialsKeepsKeyThatFailedToLoad(t *testing.T) {
+	store := useMemoryStore(t)
+	store.failing = true
+	config := Config{CredentialRefs: map[string]string{providerGemini: "memory:gemini"}}
+	loadCredentials(&config)
+	if config.GeminiAPIKey != "" {
+		t.Fatalf("GeminiAPIKey = %q", config.GeminiAPIKey)
+	}
+
+	// Any other change saves the config with the key still empty
+	saved := storeCredentials(config)
+	if saved.CredentialRefs[providerGemini] != "memory:gemini" {
+		t.Errorf("reference was dropped: %v", saved.CredentialRefs)
+	}
+	if len(store.deleted) != 0 || store.secrets["gemini"] != "gemini-secret" {
+		t.Errorf("key was deleted: %v", store.deleted)
+	}
+}
+
+func TestStoreCredentialsKeepsRefOfUnavailableStore(t *testing.T) {
+	useMemoryStore(t)
+	refs := map[string]string{providerGemini: "missing-store:gemini", providerDeepL: "memory:deepl"}
+	config := Config{CredentialRefs: maps.Clone(refs)}
+	loadCredentials(&config)
+
+	// Pretend the key was loaded before the store went away
+	knownCredentialsMu.Lock()
+	knownCredentials["missing-store:gemini"] = "gemini-secret"
+	knownCredentialsMu.Unlock()
+
+	saved := storeCredentials(config)
+	if !maps.Equal(saved.CredentialRefs, refs) {
+		t.Errorf("CredentialRefs = %v, want %v", saved.CredentialRefs, refs)
+	}
+}
+
+func TestStoreCredentialsSkipsUnchangedKey(t *testing.T) {
+	store := useMemoryStore(t)
+	config := Config{CredentialRefs: map[string]string{providerGemini: "memory:gemini"}}
+	loadCredentials(&config)
+	store.secrets["gemini"] = "changed elsewhere"
+
+	saved := storeCredentials(config)
+	if saved.GeminiAPIKey != "" {
+		t.Errorf("key written to the config file: %q", saved.GeminiAPIKey)
+	}
+	if store.secrets["gemini"] != "changed elsewhere" {
+		t.Errorf("unchanged key was written again: %q", store.secrets["gemini"])
+	}
+}
+
+func TestStoreCredentialsMovesAPIToken(t *testing.T) {
+	store := useMemoryStore(t)
+	config := Config{APIToken: "api-token-0123456789", CredentialStore: "memory"}
+	if !loadCredentials(&config) {
+		t.Error("plaintext API token was not reported for migration")
+	}
+
+	saved := storeCredentials(config)
+	if saved.APIToken != "" {
+		t.Errorf("API token written to the config file: %q", saved.APIToken)
+	}
+	if saved.CredentialRefs[credentialAPIToken] != "memory:"+credentialAPIToken {
+		t.Errorf("CredentialRefs = %v", saved.CredentialRefs)
+	}
+
+	loaded := Config{CredentialRefs: saved.CredentialRefs}
+	loadCredentials(&loaded)
+	if loaded.APIToken != "api-token-0123456789" || store.secrets[credentialAPIToken] != loaded.APIToken {
+		t.Errorf("APIToken = %q after loading", loaded.APIToken)
+	}
+}
+
+func TestLoadCredentialsMigratesOnlyMovedKeys(t *testing.T) {
+	store := useMemoryStore(t)
+	store.readOnly = true
+	config := Config{DeepLAPIKey: "deepl-secret-key", CredentialStore: "memory"}
+	if loadCredentials(&config) {
+		t.Error("migration reported although no store took the key")
+	}
+	if config.DeepLAPIKey != "deepl-secret-key" || len(config.CredentialRefs) != 0 {
+		t.Errorf("DeepLAPIKey = %q, CredentialRefs = %v", config.DeepLAPIKey, config.CredentialRefs)
+	}
+
+	store.readOnly = false
+	if !loadCredentials(&config) {
+		t.Error("moved key was not reported for migration")
+	}
+	if saved := storeCredentials(config); saved.DeepLAPIKey != "" || store.secrets["deepl"] != "deepl-secret-key" {
+		t.Errorf("DeepLAPIKey = %q in the file, %q in the store", saved.DeepLAPIKey, store.secrets["deepl"])
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// How long shutdown waits for the hotkey listener to stop
+const shutdownTimeout = 2 * time.Second
+
+// Returned when another instance holds the PID file
+var errAlreadyRunning = errors.New("already running")
+
+// Get the directory for runtime files, $XDG_RUNTIME_DIR when set, next to the config otherwise
+func runtimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Dir(getConfigPath())
+}
+
+// Path of the PID file of the headless daemon
+func pidFilePath() string {
+	return filepath.Join(runtimeDir(), appDirName+".pid")
+}
+
+// Create the PID file, fails with errAlreadyRunning while the process in it is alive.
+// A file left behind by a crash is replaced.
+func acquirePIDFile(path string) (release func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) || attempt > 0 {
+			return nil, err
+		}
+
+		if pid, ok := readPIDFile(path); ok && processAlive(pid) {
+			return nil, fmt.Errorf("%w with PID %d (%s)", errAlreadyRunning, pid, path)
+		}
+		logf("🧹 Removing stale PID file: %s\n", path)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// Read the PID stored in a PID file
+func readPIDFile(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid, err == nil && pid > 0
+}
+
+// Check if a process exists, signal 0 only checks without sending anything
+func processAlive(pid int) bool {
+	if pid == os.Getpid() {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Run the hotkey listener and translation worker without a window until SIGINT or SIGTERM.
+// Alerts and errors are shown as desktop notifications.
+func runHeadless() error {
+	config := loadConfig()
+	if errs := config.Validate(); errs != nil {
+		return fmt.Errorf("%w: config is invalid:\n%v", errConfigError, errs)
+	}
+
+	release, err := acquirePIDFile(pidFilePath())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Also keeps the window app from starting a second hotkey listener
+	instanceListener, err := acquireInstanceLock()
+	if err != nil {
+		return err
+	}
+	defer instanceListener.Close()
+
+	headlessMode.Store(true)
+	setConfig(config)
+	go watchConfigFile()
+	subscribeHotkeyChanges()
+	subscribeAPIServer()
+	if err := initPlatform(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go handleTranslationRequests()
+	go serveInstance(instanceListener, nil)
+	if runtime.GOOS == "linux" {
+		if stopDBus, err := startDBusService(); err != nil {
+			logf("⚠️ D-Bus service not available: %v\n", err)
+		} else {
+			defer stopDBus()
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		startHotkeyListener()
+	}()
+
+	logf("🎧 Running headless (PID %d)\n", os.Getpid())
+	showNotification("SuperKeyboard", fmt.Sprintf("Listening for %s", hotkeyLabel(config, actionTranslate)))
+
+	select {
+	case <-done:
+		return fmt.Errorf("hotkey listener stopped")
+	case <-ctx.Done():
+	}
+
+	logln("👋 Shutting down...")
+	stopHook()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		logln("⚠️ Hotkey listener did not stop in time")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// Name, object path and interface of the D-Bus service on the session bus
+const (
+	dbusServiceName = "io.superkeyboard.Translator"
+	dbusObjectPath  = dbus.ObjectPath("/io/superkeyboard/Translator")
+	dbusInterface   = "io.superkeyboard.Translator"
+)
+
+// Error name of failed method calls
+const dbusErrorFailed = dbusInterface + ".Error.Failed"
+
+// dbusTranslator is exported on the session bus, window manager shortcuts can call it
+// instead of the global hotkey grab. Every exported method is a D-Bus method.
+type dbusTranslator struct{}
+
+// TranslateSelection runs an action (translate, dual_translate, clipboard_translate, next_profile
+// or one from config) as if its hotkey was pressed, the result arrives as a TranslationFinished signal
+func (dbusTranslator) TranslateSelection(action string) *dbus.Error {
+	if action == "" {
+		action = actionTranslate
+	}
+	if names := actionNames(currentConfig()); !contains(names, action) {
+		return dbusError(fmt.Errorf("unknown action %q, use one of %s", action, strings.Join(names, ", ")))
+	}
+	logf("📨 D-Bus: %s\n", action)
+	if !triggerAction(action) {
+		return dbusError(fmt.Errorf("busy, too many actions are waiting (queue of %d is full), try again later", cap(actionRequests)))
+	}
+	return nil
+}
+
+// TranslateText translates text into targets (codes or labels, the selected languages when
+// empty) and returns the translations by language code
+func (dbusTranslator) TranslateText(text string, targets []string) (map[string]string, *dbus.Error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, dbusError(fmt.Errorf("no text to translate"))
+	}
+	config := currentConfig()
+	languages := languagesForCodes(config, config.SelectedLanguages)
+	if len(targets) > 0 {
+		languages = nil
+		for _, name := range targets {
+			language, ok := lookupLanguage(config, strings.TrimSpace(name))
+			if !ok {
+				return nil, dbusError(fmt.Errorf("unknown language %q", name))
+			}
+			languages = append(languages, language)
+		}
+	}
+	if len(languages) == 0 {
+		return nil, dbusError(fmt.Errorf("no target languages"))
+	}
+
+	logf("📨 D-Bus: translating to %d languages\n", len(languages))
+	source := detectSourceLanguage(config, text)
+	translations := map[string]string{}
+	var codes, texts, failed []string
+	for _, result := range translateFromSource(config, text, source, languages) {
+		if result.Err != nil {
+			logf("❌ %s translation error: %v\n", result.Language.Label, result.Err)
+			failed = append(failed, fmt.Sprintf("%s: %v", result.Language.Label, result.Err))
+			continue
+		}
+		translations[result.Language.Code] = result.Text
+		codes = append(codes, result.Language.Code)
+		texts = append(texts, result.Text)
+	}
+
+	if len(codes) == 0 {
+		err := fmt.Errorf("all translations failed: %s", strings.Join(failed, "; "))
+		broadcastError("Translation failed", err)
+		return nil, dbusError(err)
+	}
+	addHistory(historyEntry{Action: "dbus", SourceLanguage: source, Targets: codes, Text: text, Result: strings.Join(texts, "\n")})
+	return translations, nil
+}
+
+// SwitchProfile activates a profile by name
+func (dbusTranslator) SwitchProfile(name string) *dbus.Error {
+	if err := activateProfile(name); err != nil {
+		return dbusError(err)
+	}
+	return nil
+}
+
+func dbusError(err error) *dbus.Error {
+	return dbus.NewError(dbusErrorFailed, []any{redact(err.Error())})
+}
+
+// Signals of the service, added to the introspection data
+var dbusSignals = []introspect.Signal{
+	{Name: "TranslationFinished", Args: []introspect.Arg{
+		{Name: "action", Type: "s"},
+		{Name: "source_language", Type: "s"},
+		{Name: "targets", Type: "as"},
+		{Name: "text", Type: "s"},
+		{Name: "result", Type: "s"},
+	}},
+	{Name: "TranslationFailed", Args: []introspect.Arg{
+		{Name: "summary", Type: "s"},
+		{Name: "message", Type: "s"},
+	}},
+}
+
+// Register io.superkeyboard.Translator on the session bus and emit signals for finished and
+// failed translations. Only available on Linux, stop releases the name.
+func startDBusService() (stop func(), err error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("the D-Bus service is only available on Linux")
+	}
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	service := dbusTranslator{}
+	if err := conn.Export(service, dbusObjectPath, dbusInterface); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	node := &introspect.Node{
+		Name: string(dbusObjectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: dbusInterface, Methods: introspect.Methods(service), Signals: dbusSignals},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), dbusObjectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(dbusServiceName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return nil, fmt.Errorf("%s is already taken on the session bus", dbusServiceName)
+	}
+
+	// Subscribers can't be removed, they stop emitting once the connection is closed
+	subscribeHistory(func(entry historyEntry) {
+		if !conn.Connected() {
+			return
+		}
+		if err := conn.Emit(dbusObjectPath, dbusInterface+".TranslationFinished",
+			entry.Action, entry.SourceLanguage, entry.Targets, entry.Text, entry.Result); err != nil {
+			logf("⚠️ Cannot emit D-Bus signal: %v\n", err)
+		}
+	})
+	subscribeErrors(func(title string, err error) {
+		if !conn.Connected() {
+			return
+		}
+		if err := conn.Emit(dbusObjectPath, dbusInterface+".TranslationFailed", title, redact(err.Error())); err != nil {
+			logf("⚠️ Cannot emit D-Bus signal: %v\n", err)
+		}
+	})
+
+	logf("🚌 D-Bus service registered: %s\n", dbusServiceName)
+	return func() {
+		conn.ReleaseName(dbusServiceName)
+		conn.Close()
+	}, nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// What to do when the text is already in the target language
+const (
+	sameLanguageTranslate = "translate" // Translate anyway, the model improves the text
+	sameLanguageSkip      = "skip"      // Leave the text as it is
+	sameLanguageToggle    = "toggle"    // Translate to the toggle language, or to English from it
+)
+
+// Options of the same-language select in the order they are shown
+var sameLanguageModes = []string{sameLanguageTranslate, sameLanguageSkip, sameLanguageToggle}
+
+// Below this confidence the local detection is confirmed with the model if enabled
+const minDetectionConfidence = 0.5
+
+// Below this confidence the local detection is treated as unknown
+const minLocalConfidence = 0.15
+
+// LanguageDetector is implemented by backends that can identify the language of a text
+type LanguageDetector interface {
+	DetectLanguage(text string) (string, error)
+}
+
+// Most frequent character trigrams of the Latin script languages, "_" marks a word boundary
+var trigramProfiles = map[string][]string{
+	"en": {"_th", "the", "he_", "_an", "nd_", "and", "_of", "of_", "_to", "to_", "ing", "ng_", "_in", "in_", "is_", "_is", "ion", "_a_", "ed_", "_it", "it_", "er_", "re_", "at_", "on_", "you", "ou_", "_yo", "_wh", "hat", "tha", "_be", "es_", "for", "_fo", "or_", "ent", "_wi", "wit", "ith"},
+	"fr": {"_le", "le_", "_de", "de_", "es_", "_la", "la_", "ent", "_et", "et_", "les", "_un", "_qu", "que", "ue_", "_pa", "des", "_es", "est", "st_", "ion", "tio", "_po", "our", "_vo", "vou", "ous", "_je", "je_", "_ce", "_en", "en_", "ne_", "_ne", "ais", "re_", "eur", "_co", "men", "ant"},
+	"de": {"en_", "er_", "_de", "der", "die", "ie_", "_di", "ch_", "ich", "sch", "_un", "und", "nd_", "ein", "_ei", "_ge", "gen", "den", "_da", "das", "as_", "_is", "ist", "st_", "_ni", "nic", "cht", "ht_", "_zu", "zu_", "ung", "ng_", "_mi", "mit", "it_", "_au", "auf", "uf_", "_we", "ten"},
+	"es": {"_de", "de_", "_la", "la_", "os_", "_el", "el_", "_qu", "que", "ue_", "es_", "_en", "en_", "as_", "_lo", "los", "_y_", "_co", "con", "ent", "_se", "ion", "ón_", "ció", "_un", "una", "_po", "por", "or_", "ado", "_es", "est", "_pa", "par", "ara", "_su", "_me", "_no", "no_", "ra_"},
+}
+
+// Letters only used by Vietnamese among the Latin script languages
+const vietnameseLetters = "ăđơưạảấầẩẫậắằẳẵặẹẻẽếềểễệỉịọỏốồổỗộớờởỡợụủứừửữựỳỵỷỹ"
+
+// Characters that differ between Traditional and Simplified Chinese
+const (
+	traditionalChineseChars = "這們說對時國會為與來學體點還電話開關見長門問間東車馬鳥魚"
+	simplifiedChineseChars  = "这们说对时国会为与来学体点还电话开关见长门问间东车马鸟鱼"
+)
+
+// Detect the language of a text locally, returns a BCP-47 code and a confidence between 0 and 1.
+// Non-Latin scripts are identified by their Unicode ranges, Latin script languages by trigrams.
+func detectLanguageLocal(text string) (string, float64) {
+	var latin, han, kana, hangul, thai, letters int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			continue
+		}
+		letters++
+	}
+	if letters == 0 {
+		return "", 0
+	}
+
+	share := func(count int) float64 { return float64(count) / float64(letters) }
+	switch {
+	case kana > 0 && kana+han >= latin:
+		// Japanese mixes kana with kanji, Chinese has no kana
+		return "ja", share(kana + han)
+	case hangul > 0 && hangul >= latin && hangul >= han:
+		return "ko", share(hangul)
+	case thai > 0 && thai >= latin && thai >= han:
+		return "th", share(thai)
+	case han > 0 && han >= latin:
+		if countRunes(text, traditionalChineseChars) > countRunes(text, simplifiedChineseChars) {
+			return "zh-Hant", share(han)
+		}
+		return "zh-Hans", share(han)
+	}
+
+	lower := strings.ToLower(text)
+	if words := strings.Fields(lower); len(words) > 0 {
+		vietnameseWords := 0
+		for _, word := range words {
+			if strings.ContainsAny(word, vietnameseLetters) {
+				vietnameseWords++
+			}
+		}
+		if float64(vietnameseWords)/float64(len(words)) >= 0.2 {
+			return "vi", share(latin)
+		}
+	}
+
+	code, confidence := detectLatinLanguage(lower)
+	return code, confidence * share(latin)
+}
+
+// Score the trigram profiles against a lower case text, confidence is the margin of the best profile
+func detectLatinLanguage(text string) (string, float64) {
+	trigrams := map[string]int{}
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune("_" + word + "_")
+		for i := 0; i+3 <= len(runes); i++ {
+			trigrams[string(runes[i:i+3])]++
+		}
+	}
+
+	best, bestScore, secondScore := "", 0, 0
+	for code, profile := range trigramProfiles {
+		score := 0
+		for _, trigram := range profile {
+			score += trigrams[trigram]
+		}
+		if score > bestScore || (score == bestScore && code < best) {
+			best, bestScore, secondScore = code, score, bestScore
+		} else if score > secondScore {
+			secondScore = score
+		}
+	}
+
+	if bestScore < 2 {
+		return "", 0
+	}
+	return best, float64(bestScore-secondScore) / float64(bestScore)
+}
+
+// Count the runes of text that are in set
+func countRunes(text string, set string) int {
+	count := 0
+	for _, r := range text {
+		if strings.ContainsRune(set, r) {
+			count++
+		}
+	}
+	return count
+}
+
+// Detect the source language of a text with the languages and model of config, confirming unsure
+// results with the model when enabled. Returns an empty string when the language is unknown.
+func detectSourceLanguage(config Config, text string) string {
+	code, confidence := detectLanguageLocal(text)
+	if confidence < minLocalConfidence {
+		code = ""
+	}
+	if confidence >= minDetectionConfidence || !config.ModelLanguageDetection {
+		logf("🔎 Detected language: %q (confidence %.2f)\n", code, confidence)
+		return code
+	}
+
+	translator, err := newTranslator(config)
+	if err != nil {
+		return code
+	}
+	detector, ok := translator.(LanguageDetector)
+	if !ok {
+		return code
+	}
+
+	modelCode, err := detector.DetectLanguage(text)
+	if err != nil {
+		logf("⚠️ Model language detection failed, using %q: %v\n", code, err)
+		return code
+	}
+	if language, ok := findLanguage(config, modelCode); ok {
+		modelCode = language.Code
+	}
+	logf("🔎 Detected language: %q (model, local guess %q)\n", modelCode, code)
+	return modelCode
+}
+
+// Check if a detected source language is the target language
+func isSameLanguage(source string, target Language) bool {
+	return source != "" && strings.EqualFold(source, target.Code)
+}
+
+// Pick the target for a text in the source language, skip is true when nothing should be translated
+func resolveTarget(config Config, source string, target Language) (Language, bool) {
+	if !isSameLanguage(source, target) {
+		return target, false
+	}
+
+	switch config.SameLanguageMode {
+	case sameLanguageSkip:
+		return target, true
+	case sameLanguageToggle:
+		// English text goes to the toggle language, anything else goes to English
+		next := "en"
+		if strings.EqualFold(target.Code, "en") {
+			next = config.ToggleLanguage
+			if next == "" {
+				next = "vi"
+			}
+		}
+		if language, ok := findLanguage(config, next); ok {
+			return language, false
+		}
+	}
+	return target, false
+}
+
+// Translate into all targets, targets in the source language keep the original text unless
+// the same-language mode is translate
+func translateFromSource(config Config, text string, source string, targets []Language) []languageTranslation {
+	if config.SameLanguageMode == "" || config.SameLanguageMode == sameLanguageTranslate {
+		return translateToLanguages(config, text, targets)
+	}
+
+	var toTranslate []Language
+	for _, target := range targets {
+		if !isSameLanguage(source, target) {
+			toTranslate = append(toTranslate, target)
+		}
+	}
+	translated := translateToLanguages(config, text, toTranslate)
+
+	// Merge both back in the order of targets
+	results := make([]languageTranslation, 0, len(targets))
+	for _, target := range targets {
+		if isSameLanguage(source, target) {
+			logf("⏭️ Text is already in %s, keeping it as it is\n", target.Name)
+			results = append(results, languageTranslation{Language: target, Text: strings.TrimSpace(text)})
+			continue
+		}
+		results = append(results, translated[0])
+		translated = translated[1:]
+	}
+	return results
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Number of translations kept in history
+const maxHistoryEntries = 100
+
+// A finished (or skipped) hotkey action
+type historyEntry struct {
+	Time           time.Time `json:"time"`
+	Action         string    `json:"action"`
+	SourceLanguage string    `json:"source_language,omitempty"` // Detected BCP-47 code, empty if unknown
+	Targets        []string  `json:"targets"`
+	Text           string    `json:"text"`
+	Result         string    `json:"result"`
+	Skipped        bool      `json:"skipped,omitempty"`
+}
+
+// In-memory history of recent translations, newest last
+var (
+	historyMu          sync.Mutex
+	history            []historyEntry
+	historySubscribers []func(entry historyEntry)
+)
+
+// Call fn with every entry added to the history
+func subscribeHistory(fn func(entry historyEntry)) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	historySubscribers = append(historySubscribers, fn)
+}
+
+// Add an entry to the history, dropping the oldest when it is full
+func addHistory(entry historyEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	historyMu.Lock()
+	history = append(history, entry)
+	if len(history) > maxHistoryEntries {
+		history = history[len(history)-maxHistoryEntries:]
+	}
+	subscribers := slices.Clone(historySubscribers)
+	historyMu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(entry)
+	}
+}
+
+// Get a copy of the history, newest last
+func recentHistory() []historyEntry {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	return append([]historyEntry(nil), history...)
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	hook "github.com/robotn/gohook"
+)
+
+// Hotkey actions that can be bound in config
+const (
+	actionTranslate          = "translate"           // Translate selection to English
+	actionDualTranslate      = "dual_translate"      // Select all and translate to the selected languages
+	actionClipboardTranslate = "clipboard_translate" // Translate selection to the G language and show an alert
+	actionNextProfile        = "next_profile"        // Switch to the next profile
+)
+
+// Built-in hotkey actions in the order they are shown in the UI, actions from config follow them
+var hotkeyActions = []string{actionTranslate, actionDualTranslate, actionClipboardTranslate, actionNextProfile}
+
+// Default accelerators for each action, actions without a default have no hotkey until one is recorded
+func defaultHotkeys() map[string]string {
+	return map[string]string{
+		actionTranslate:          "ctrl+alt+h",
+		actionDualTranslate:      "ctrl+alt+j",
+		actionClipboardTranslate: "ctrl+alt+g",
+	}
+}
+
+// Modifier flags of a hotkey, left and right keys are treated the same
+const (
+	modShift uint16 = 1 << iota
+	modCtrl
+	modAlt
+	modMeta
+)
+
+// Modifier bits of gohook (libuiohook) event masks
+const (
+	maskShiftL uint16 = 1 << 0
+	maskCtrlL  uint16 = 1 << 1
+	maskMetaL  uint16 = 1 << 2
+	maskAltL   uint16 = 1 << 3
+	maskShiftR uint16 = 1 << 4
+	maskCtrlR  uint16 = 1 << 5
+	maskMetaR  uint16 = 1 << 6
+	maskAltR   uint16 = 1 << 7
+)
+
+// Keycodes of the modifier keys themselves, these cannot be the key of a hotkey
+var modifierKeycodes = map[uint16]bool{
+	0x002A: true, // Shift L
+	0x0036: true, // Shift R
+	0x001D: true, // Control L
+	0x0E1D: true, // Control R
+	0x0038: true, // Alt L
+	0x0E38: true, // Alt R
+	0x0E5B: true, // Meta L
+	0x0E5C: true, // Meta R
+}
+
+// Hotkey is a parsed accelerator: one key plus a set of modifiers
+type Hotkey struct {
+	Keycode   uint16
+	Modifiers uint16
+}
+
+// Get the modifier flag for a name in an accelerator string
+func modifierForName(name string) (uint16, bool) {
+	switch name {
+	case "ctrl", "control":
+		return modCtrl, true
+	case "alt", "option", "opt":
+		return modAlt, true
+	case "shift":
+		return modShift, true
+	case "cmd", "command", "super", "win", "meta":
+		return modMeta, true
+	case "cmdorctrl", "commandorcontrol":
+		// Command on macOS, Control everywhere else
+		if runtime.GOOS == "darwin" {
+			return modMeta, true
+		}
+		return modCtrl, true
+	}
+	return 0, false
+}
+
+// Parse an accelerator string like "ctrl+alt+h" into a Hotkey
+func parseAccelerator(accelerator string) (Hotkey, error) {
+	var hotkey Hotkey
+
+	normalized := strings.ToLower(strings.ReplaceAll(accelerator, " ", ""))
+	if normalized == "" {
+		return hotkey, fmt.Errorf("empty hotkey")
+	}
+
+	parts := strings.Split(normalized, "+")
+	for i, part := range parts {
+		if part == "" {
+			return hotkey, fmt.Errorf("invalid hotkey %q", accelerator)
+		}
+		if mod, ok := modifierForName(part); ok {
+			hotkey.Modifiers |= mod
+			continue
+		}
+		if i != len(parts)-1 {
+			return hotkey, fmt.Errorf("invalid hotkey %q: key %q must come after the modifiers", accelerator, part)
+		}
+		code, ok := hook.Keycode[part]
+		if !ok || modifierKeycodes[code] {
+			return hotkey, fmt.Errorf("invalid hotkey %q: unknown key %q", accelerator, part)
+		}
+		hotkey.Keycode = code
+	}
+
+	if hotkey.Keycode == 0 {
+		return hotkey, fmt.Errorf("invalid hotkey %q: missing key", accelerator)
+	}
+	if hotkey.Modifiers == 0 {
+		return hotkey, fmt.Errorf("invalid hotkey %q: at least one modifier is required", accelerator)
+	}
+	return hotkey, nil
+}
+
+// Get the modifier flags of a gohook event, ignoring lock keys and mouse buttons
+func eventModifiers(mask uint16) uint16 {
+	var mods uint16
+	if mask&(maskShiftL|maskShiftR) != 0 {
+		mods |= modShift
+	}
+	if mask&(maskCtrlL|maskCtrlR) != 0 {
+		mods |= modCtrl
+	}
+	if mask&(maskAltL|maskAltR) != 0 {
+		mods |= modAlt
+	}
+	if mask&(maskMetaL|maskMetaR) != 0 {
+		mods |= modMeta
+	}
+	return mods
+}
+
+// Check if a key event triggers this hotkey
+func (h Hotkey) matches(ev hook.Event) bool {
+	return ev.Keycode == h.Keycode && eventModifiers(ev.Mask) == h.Modifiers
+}
+
+// Get the key name of a keycode, preferring the shortest name
+func keyNameForKeycode(code uint16) string {
+	var names []string
+	for name, c := range hook.Keycode {
+		if c == code {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("0x%x", code)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return names[0]
+}
+
+// Accelerator string of the hotkey as stored in config
+func (h Hotkey) String() string {
+	var parts []string
+	if h.Modifiers&modCtrl != 0 {
+		parts = append(parts, "ctrl")
+	}
+	if h.Modifiers&modAlt != 0 {
+		parts = append(parts, "alt")
+	}
+	if h.Modifiers&modShift != 0 {
+		parts = append(parts, "shift")
+	}
+	if h.Modifiers&modMeta != 0 {
+		parts = append(parts, "cmd")
+	}
+	return strings.Join(append(parts, keyNameForKeycode(h.Keycode)), "+")
+}
+
+// Human readable name of the hotkey using the platform's modifier names
+func (h Hotkey) Label() string {
+	ctrl, alt, meta := "Ctrl", "Alt", "Super"
+	if runtime.GOOS == "darwin" {
+		ctrl, alt, meta = "Control", "Option", "Command"
+	}
+
+	var parts []string
+	if h.Modifiers&modCtrl != 0 {
+		parts = append(parts, ctrl)
+	}
+	if h.Modifiers&modAlt != 0 {
+		parts = append(parts, alt)
+	}
+	if h.Modifiers&modShift != 0 {
+		parts = append(parts, "Shift")
+	}
+	if h.Modifiers&modMeta != 0 {
+		parts = append(parts, meta)
+	}
+	return strings.Join(append(parts, strings.ToUpper(keyNameForKeycode(h.Keycode))), " + ")
+}
+
+// Label of the accelerator configured for an action, or the raw string if it is invalid
+func hotkeyLabel(config Config, action string) string {
+	if config.Hotkeys[action] == "" {
+		return "Not set"
+	}
+	hotkey, err := parseAccelerator(config.Hotkeys[action])
+	if err != nil {
+		return config.Hotkeys[action]
+	}
+	return hotkey.Label()
+}
+
+// A hotkey bound to an action
+type hotkeyBinding struct {
+	action string
+	hotkey Hotkey
+}
+
+// Parse all hotkeys of a config, rejecting invalid and conflicting bindings
+func parseHotkeys(hotkeys map[string]string) ([]hotkeyBinding, error) {
+	var bindings []hotkeyBinding
+	usedBy := map[Hotkey]string{}
+
+	actions := slices.Collect(maps.Keys(hotkeys))
+	sortActionNames(actions)
+	for _, action := range actions {
+		accelerator := hotkeys[action]
+		if accelerator == "" {
+			continue // Action has no hotkey
+		}
+		hotkey, err := parseAccelerator(accelerator)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", action, err)
+		}
+		if other, exists := usedBy[hotkey]; exists {
+			return nil, fmt.Errorf("%s: %s is already used by %s", action, hotkey.Label(), other)
+		}
+		usedBy[hotkey] = action
+		bindings = append(bindings, hotkeyBinding{action: action, hotkey: hotkey})
+	}
+	return bindings, nil
+}
+
+// Fill in missing hotkeys with the defaults of built-in actions and the hotkeys of config actions
+func applyDefaultHotkeys(config *Config) {
+	if config.Hotkeys == nil {
+		config.Hotkeys = map[string]string{}
+	}
+	for action, accelerator := range defaultHotkeys() {
+		if _, ok := config.Hotkeys[action]; !ok {
+			config.Hotkeys[action] = accelerator
+		}
+	}
+	for _, action := range config.Actions {
+		if _, ok := config.Hotkeys[action.Name]; !ok && action.Hotkey != "" {
+			config.Hotkeys[action.Name] = action.Hotkey
+		}
+	}
+}
+
+// Bindings used by the hotkey listener
+var (
+	activeHotkeysMu sync.RWMutex
+	activeHotkeys   []hotkeyBinding
+)
+
+// Replace the bindings used by the hotkey listener
+func setActiveHotkeys(hotkeys map[string]string) error {
+	bindings, err := parseHotkeys(hotkeys)
+	if err != nil {
+		return err
+	}
+	activeHotkeysMu.Lock()
+	activeHotkeys = bindings
+	activeHotkeysMu.Unlock()
+	return nil
+}
+
+// Find the action bound to a key event
+func matchHotkey(ev hook.Event) (hotkeyBinding, bool) {
+	activeHotkeysMu.RLock()
+	defer activeHotkeysMu.RUnlock()
+	for _, binding := range activeHotkeys {
+		if binding.hotkey.matches(ev) {
+			return binding, true
+		}
+	}
+	return hotkeyBinding{}, false
+}
+
+// Whether the gohook listener is running, recording needs its events
+var hotkeyListenerRunning atomic.Bool
+
+// Pending hotkey recording, the listener sends the next key press to it
+var (
+	hotkeyRecorderMu sync.Mutex
+	hotkeyRecorder   chan Hotkey
+)
+
+// Start recording, the returned channel receives the next hotkey pressed
+func recordNextHotkey() <-chan Hotkey {
+	hotkeyRecorderMu.Lock()
+	defer hotkeyRecorderMu.Unlock()
+	hotkeyRecorder = make(chan Hotkey, 1)
+	return hotkeyRecorder
+}
+
+// Stop a pending recording
+func cancelHotkeyRecording() {
+	hotkeyRecorderMu.Lock()
+	defer hotkeyRecorderMu.Unlock()
+	if hotkeyRecorder != nil {
+		close(hotkeyRecorder)
+		hotkeyRecorder = nil
+	}
+}
+
+// Send a key event to a pending recording, returns true if the event was consumed
+func recordHotkeyEvent(ev hook.Event) bool {
+	hotkeyRecorderMu.Lock()
+	defer hotkeyRecorderMu.Unlock()
+	if hotkeyRecorder == nil {
+		return false
+	}
+	// Wait for the actual key, modifiers are pressed first
+	if modifierKeycodes[ev.Keycode] {
+		return true
+	}
+	hotkeyRecorder <- Hotkey{Keycode: ev.Keycode, Modifiers: eventModifiers(ev.Mask)}
+	close(hotkeyRecorder)
+	hotkeyRecorder = nil
+	return true
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Commands a second launch can forward to the running instance
+const (
+	instanceShow          = "show"
+	instanceProfile       = "profile"
+	instanceTranslateFile = "translate-file"
+)
+
+// How long a forwarded command may take, translating a file waits for the provider
+const (
+	instanceReadTimeout  = 5 * time.Second
+	instanceReplyTimeout = 2 * time.Minute
+)
+
+// Returned by sendToInstance when no instance is listening
+var errNoInstance = errors.New("no running instance")
+
+// instanceRequest is one command sent over the instance socket, one JSON line per connection
+type instanceRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// instanceReply answers an instanceRequest, Error is empty on success
+type instanceReply struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Path of the socket the running instance listens on
+func instanceSocketPath() string {
+	return filepath.Join(runtimeDir(), appDirName+".sock")
+}
+
+// Become the single running instance by listening on the instance socket. Fails with
+// errAlreadyRunning when another instance answers; a socket left behind by a crash is replaced.
+func acquireInstanceLock() (net.Listener, error) {
+	path := instanceSocketPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err == nil {
+		return listener, nil
+	}
+	if conn, dialErr := net.DialTimeout("unix", path, time.Second); dialErr == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w (%s)", errAlreadyRunning, path)
+	}
+
+	logf("🧹 Removing stale instance socket: %s\n", path)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+// Send a command to the running instance and wait for its reply
+func sendToInstance(command string, args ...string) (string, error) {
+	conn, err := net.DialTimeout("unix", instanceSocketPath(), time.Second)
+	if err != nil {
+		return "", errNoInstance
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(instanceReplyTimeout))
+
+	if err := json.NewEncoder(conn).Encode(instanceRequest{Command: command, Args: args}); err != nil {
+		return "", err
+	}
+	var reply instanceReply
+	if err := json.NewDecoder(conn).Decode(&reply); err != nil {
+		return "", fmt.Errorf("no reply from the running instance: %v", err)
+	}
+	if reply.Error != "" {
+		return "", errors.New(reply.Error)
+	}
+	return reply.Message, nil
+}
+
+// Answer commands of later launches until the listener is closed.
+// showWindow is nil when running headless.
+func serveInstance(listener net.Listener, showWindow func()) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logf("❌ Instance socket error: %v\n", err)
+			}
+			return
+		}
+		go handleInstanceConn(conn, showWindow)
+	}
+}
+
+func handleInstanceConn(conn net.Conn, showWindow func()) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(instanceReadTimeout))
+	request, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		logf("❌ Cannot read instance command: %v\n", err)
+		return
+	}
+	var req instanceRequest
+	var reply instanceReply
+	if err := json.Unmarshal(request, &req); err != nil {
+		reply.Error = fmt.Sprintf("bad command: %v", err)
+	} else {
+		logf("📨 Command from another launch: %s %s\n", req.Command, strings.Join(req.Args, " "))
+		message, err := runInstanceCommand(req, showWindow)
+		reply.Message = message
+		if err != nil {
+			reply.Error = redact(err.Error())
+		}
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(instanceReadTimeout))
+	if err := json.NewEncoder(conn).Encode(reply); err != nil {
+		logf("❌ Cannot answer instance command: %v\n", err)
+	}
+}
+
+// Run a forwarded command in this instance
+func runInstanceCommand(req instanceRequest, showWindow func()) (string, error) {
+	switch req.Command {
+	case instanceShow:
+		if showWindow == nil {
+			return "", fmt.Errorf("the running instance is headless and has no window")
+		}
+		showWindow()
+		return "", nil
+
+	case instanceProfile:
+		if len(req.Args) != 1 {
+			return "", fmt.Errorf("profile needs a profile name")
+		}
+		if err := activateProfile(req.Args[0]); err != nil {
+			return "", err
+		}
+		showNotification("Profile", fmt.Sprintf("Switched to profile: %s", currentConfig().ActiveProfile))
+		return fmt.Sprintf("switched to profile %s", currentConfig().ActiveProfile), nil
+
+	case instanceTranslateFile:
+		if len(req.Args) != 1 {
+			return "", fmt.Errorf("translate-file needs a file path")
+		}
+		paths, err := translateFile(req.Args[0])
+		if err != nil {
+			notifyError("Translation failed", err)
+			return "", err
+		}
+		showNotification("Translation", fmt.Sprintf("Translated %s", filepath.Base(req.Args[0])))
+		return strings.Join(paths, "\n"), nil
+
+	default:
+		return "", fmt.Errorf("unknown command %q", req.Command)
+	}
+}
+
+// Translate a text file into the selected languages. Each translation is written next to it
+// with the language code before the extension, e.g. notes.ja.txt; returns the written paths.
+func translateFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+
+	config := currentConfig()
+	targets := languagesForCodes(config, config.SelectedLanguages)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no target languages selected")
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	var paths []string
+	var failed []string
+	for _, result := range translateFromSource(config, text, detectSourceLanguage(config, text), targets) {
+		if result.Err != nil {
+			logf("❌ %s translation error: %v\n", result.Language.Label, result.Err)
+			failed = append(failed, result.Language.Label)
+			continue
+		}
+		outPath := base + "." + result.Language.Code + ext
+		if err := os.WriteFile(outPath, []byte(result.Text+"\n"), 0644); err != nil {
+			return paths, err
+		}
+		logf("📄 Wrote %s\n", outPath)
+		paths = append(paths, outPath)
+	}
+	if len(failed) > 0 {
+		return paths, fmt.Errorf("translation into %s failed", strings.Join(failed, ", "))
+	}
+	return paths, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Language is an entry of the language registry
+type Language struct {
+	Code       string `json:"code"`        // BCP-47 code, e.g. "zh-Hans"
+	Label      string `json:"label"`       // Short label for checkboxes and the [LANG] prefix, e.g. "ZH-CN"
+	Name       string `json:"name"`        // English display name
+	NativeName string `json:"native_name"` // Name in the language itself
+	PromptName string `json:"prompt_name"` // Name used in translation prompts
+}
+
+// Languages known without any configuration
+var builtinLanguages = []Language{
+	{Code: "en", Label: "EN", Name: "English", NativeName: "English", PromptName: "English"},
+	{Code: "vi", Label: "VN", Name: "Vietnamese", NativeName: "Tiếng Việt", PromptName: "Vietnamese"},
+	{Code: "ja", Label: "JP", Name: "Japanese", NativeName: "日本語", PromptName: "Japanese"},
+	{Code: "ko", Label: "KO", Name: "Korean", NativeName: "한국어", PromptName: "Korean"},
+	{Code: "zh-Hans", Label: "ZH-CN", Name: "Chinese (Simplified)", NativeName: "简体中文", PromptName: "Simplified Chinese"},
+	{Code: "zh-Hant", Label: "ZH-TW", Name: "Chinese (Traditional)", NativeName: "繁體中文", PromptName: "Traditional Chinese"},
+	{Code: "th", Label: "TH", Name: "Thai", NativeName: "ไทย", PromptName: "Thai"},
+	{Code: "fr", Label: "FR", Name: "French", NativeName: "Français", PromptName: "French"},
+	{Code: "de", Label: "DE", Name: "German", NativeName: "Deutsch", PromptName: "German"},
+	{Code: "es", Label: "ES", Name: "Spanish", NativeName: "Español", PromptName: "Spanish"},
+}
+
+// Languages shown in the UI until the user adds more
+var defaultLanguageCodes = []string{"en", "vi", "ja"}
+
+// Codes used by older config files
+var legacyLanguageCodes = map[string]string{
+	"EN": "en",
+	"VN": "vi",
+	"JP": "ja",
+}
+
+// Get all languages of the registry, custom languages from config replace built-in ones with the same code
+func allLanguages(config Config) []Language {
+	var languages []Language
+	for _, builtin := range builtinLanguages {
+		if _, custom := findCustomLanguage(config, builtin.Code); !custom {
+			languages = append(languages, builtin)
+		}
+	}
+	return append(languages, config.CustomLanguages...)
+}
+
+// Find a custom language of the config by code
+func findCustomLanguage(config Config, code string) (Language, bool) {
+	for _, language := range config.CustomLanguages {
+		if strings.EqualFold(language.Code, code) {
+			return language, true
+		}
+	}
+	return Language{}, false
+}
+
+// Find a language of the registry by code, legacy codes like "JP" are accepted
+func findLanguage(config Config, code string) (Language, bool) {
+	code = normalizeLanguageCode(code)
+	for _, language := range allLanguages(config) {
+		if strings.EqualFold(language.Code, code) {
+			return language, true
+		}
+	}
+	return Language{}, false
+}
+
+// Find a language by code or by its label, e.g. "ja", "JP" or "ZH-CN"
+func lookupLanguage(config Config, name string) (Language, bool) {
+	if language, ok := findLanguage(config, name); ok {
+		return language, true
+	}
+	for _, language := range allLanguages(config) {
+		if strings.EqualFold(language.Label, name) {
+			return language, true
+		}
+	}
+	return Language{}, false
+}
+
+// Convert legacy codes to BCP-47 codes
+func normalizeLanguageCode(code string) string {
+	if normalized, ok := legacyLanguageCodes[code]; ok {
+		return normalized
+	}
+	return code
+}
+
+// Get the languages shown in the UI, in the order they were added
+func enabledLanguages(config Config) []Language {
+	codes := config.Languages
+	if len(codes) == 0 {
+		codes = defaultLanguageCodes
+	}
+
+	var languages []Language
+	for _, code := range codes {
+		if language, ok := findLanguage(config, code); ok {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// Get the registry languages of a list of codes, unknown codes are skipped
+func languagesForCodes(config Config, codes []string) []Language {
+	var languages []Language
+	for _, code := range codes {
+		if language, ok := findLanguage(config, code); ok {
+			languages = append(languages, language)
+		} else {
+			logf("⚠️ Unknown language code: %s\n", code)
+		}
+	}
+	return languages
+}
+
+// Make sure the languages used by the config are shown in the UI
+func enableUsedLanguages(config *Config) {
+	if len(config.Languages) == 0 {
+		config.Languages = append([]string{}, defaultLanguageCodes...)
+	}
+	for _, code := range append(append([]string{}, config.SelectedLanguages...), config.ClipboardLanguage) {
+		if code != "" && !contains(config.Languages, code) {
+			config.Languages = append(config.Languages, code)
+		}
+	}
+}
+
+// Add a language to the UI, registering it as a custom language when it is not in the registry yet
+func addLanguage(config *Config, language Language) error {
+	language.Code = strings.TrimSpace(language.Code)
+	if language.Code == "" {
+		return fmt.Errorf("language code is required")
+	}
+	if contains(config.Languages, language.Code) {
+		return fmt.Errorf("%s is already added", language.Code)
+	}
+
+	if _, known := findLanguage(*config, language.Code); !known {
+		if language.Name == "" {
+			return fmt.Errorf("language name is required")
+		}
+		if language.Label == "" {
+			language.Label = strings.ToUpper(language.Code)
+		}
+		if language.NativeName == "" {
+			language.NativeName = language.Name
+		}
+		if language.PromptName == "" {
+			language.PromptName = language.Name
+		}
+		// Labels pick the language in the radio group, so they must be unique
+		for _, other := range allLanguages(*config) {
+			if strings.EqualFold(other.Label, language.Label) {
+				return fmt.Errorf("label %s is already used by %s", language.Label, other.Name)
+			}
+		}
+		config.CustomLanguages = append(config.CustomLanguages, language)
+	}
+
+	config.Languages = append(config.Languages, language.Code)
+	return nil
+}
+
+// Languages of the registry that are not shown in the UI yet
+func addableLanguages(config Config) []Language {
+	var languages []Language
+	for _, language := range allLanguages(config) {
+		if !contains(config.Languages, language.Code) {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// Text shown for a language in lists, e.g. "Korean (한국어)"
+func (l Language) DisplayName() string {
+	if l.NativeName == "" || l.NativeName == l.Name {
+		return l.Name
+	}
+	return fmt.Sprintf("%s (%s)", l.Name, l.NativeName)
+}
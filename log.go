@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Shorter strings are not treated as secrets, they would redact ordinary text
+const minSecretLength = 8
+
+// Text that replaces secrets in log output
+const redactedText = "[REDACTED]"
+
+// Destination of all log output
+var logOutput io.Writer = os.Stdout
+
+// Secrets removed from log output, set from the API keys in config
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// Fields of a config that hold secrets by JSON name, kept out of log output and `config get`
+func secretFields(config *Config) map[string]*string {
+	return map[string]*string{
+		"gemini_api_key":         &config.GeminiAPIKey,
+		"openai_api_key":         &config.OpenAIAPIKey,
+		"deepl_api_key":          &config.DeepLAPIKey,
+		"libretranslate_api_key": &config.LibreTranslateAPIKey,
+		"api_token":              &config.APIToken,
+	}
+}
+
+// Replace the secrets removed from log output with the API keys and token of a config
+func setSecrets(config Config) {
+	var keys []string
+	for _, key := range secretFields(&config) {
+		if len(*key) >= minSecretLength {
+			keys = append(keys, *key)
+		}
+	}
+	// Longest first, so a key containing another one is fully redacted
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	secretsMu.Lock()
+	secrets = keys
+	secretsMu.Unlock()
+}
+
+// Remove all known secrets from a string
+func redact(text string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, redactedText)
+	}
+	return text
+}
+
+// Print a formatted log message with secrets removed
+func logf(format string, args ...any) {
+	fmt.Fprint(logOutput, redact(fmt.Sprintf(format, args...)))
+}
+
+// Print a log line with secrets removed
+func logln(args ...any) {
+	fmt.Fprint(logOutput, redact(fmt.Sprintln(args...)))
+}
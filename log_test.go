@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testSecretKey = "AIzaSyTESTKEY-1234567890abcdef"
+
+// Send log output to a buffer with testSecretKey as the Gemini key
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var output bytes.Buffer
+	logOutput = &output
+	setSecrets(Config{GeminiAPIKey: testSecretKey})
+	t.Cleanup(func() {
+		logOutput = io.Discard
+		setSecrets(Config{})
+	})
+	return &output
+}
+
+func TestLogRedactsSecrets(t *testing.T) {
+	output := captureLog(t)
+
+	statusErr := &httpStatusError{StatusCode: 400, Body: `{"error":{"message":"API key not valid: ` + testSecretKey + `"}}`}
+	urlErr := &url.Error{
+		Op:  "Post",
+		URL: "https://generativelanguage.googleapis.com/v1beta/models/gemini:generateContent?key=" + testSecretKey,
+		Err: errors.New("connection refused"),
+	}
+
+	logf("❌ Error: %v\n", fmt.Errorf("translation failed: %w", statusErr))
+	logf("❌ Error: %v\n", fmt.Errorf("translation failed: %w", urlErr))
+	logf("🔑 Key: %s, prefix %q\n", testSecretKey, "x"+testSecretKey)
+	logln("❌ Error:", urlErr)
+	logln(testSecretKey + testSecretKey)
+
+	if strings.Contains(output.String(), testSecretKey) {
+		t.Errorf("log output contains the API key:\n%s", output)
+	}
+	if got := strings.Count(output.String(), redactedText); got != 7 {
+		t.Errorf("log output has %d redactions, want 7:\n%s", got, output)
+	}
+}
+
+func TestLogRedactsProviderErrors(t *testing.T) {
+	output := captureLog(t)
+
+	// A backend that echoes the key it was sent in its error body
+	translator := newTestTranslator(t, providerGemini, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `{"error":"key %s is not allowed"}`, r.Header.Get("x-goog-api-key"))
+	})
+	translator.(*geminiTranslator).apiKey = testSecretKey
+
+	_, err := translator.Translate("Hello", testLanguage(t, "vi"))
+	if err == nil || !strings.Contains(err.Error(), testSecretKey) {
+		t.Fatalf("Translate() error = %v, want the echoed key", err)
+	}
+	logf("❌ %s translation error: %v\n", "VN", err)
+
+	if strings.Contains(output.String(), testSecretKey) {
+		t.Errorf("log output contains the API key:\n%s", output)
+	}
+	if !strings.Contains(output.String(), redactedText) {
+		t.Errorf("log output has no redaction:\n%s", output)
+	}
+}
+
+func TestLogKeepsShortValues(t *testing.T) {
+	output := captureLog(t)
+	setSecrets(Config{GeminiAPIKey: testSecretKey, OpenAIAPIKey: "abc"})
+
+	logln("abc is not a secret")
+	if got := output.String(); got != "abc is not a secret\n" {
+		t.Errorf("log output = %q", got)
+	}
+}
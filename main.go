@@ -1,22 +1,24 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/joho/godotenv"
@@ -25,36 +27,93 @@ import (
 
 // Config struct for storing API key and model
 type Config struct {
+	Version           int      `json:"version"` // Format version of the file, see configMigrations
 	GeminiAPIKey      string   `json:"gemini_api_key"`
 	Model             string   `json:"model"`
 	SelectedLanguages []string `json:"selected_languages"`
 	IncludePrefix     bool     `json:"include_prefix"`
-	GLanguage         string   `json:"g_language"` // Language for Control+Option+G hotkey
+	ClipboardLanguage string   `json:"clipboard_language"` // Language code for the clipboard translate hotkey
+
+	// BCP-47 codes of the languages shown in the UI, and languages added by the user
+	Languages       []string   `json:"languages,omitempty"`
+	CustomLanguages []Language `json:"custom_languages,omitempty"`
+
+	// Leave the translation on the clipboard instead of restoring what was there before
+	KeepTranslationInClipboard bool `json:"keep_translation_in_clipboard"`
+	// How long to wait for copied text to reach the clipboard, 0 uses the default of 1000ms
+	CopyTimeoutMs int `json:"copy_timeout_ms,omitempty"`
+	// Languages translated at the same time when each needs its own request, 0 uses the default of 3
+	TranslationWorkers int `json:"translation_workers,omitempty"`
+
+	// Source language detection: what to do with text already in the target language
+	// (translate, skip or toggle), the toggle target for English text, and model confirmation
+	SameLanguageMode       string `json:"same_language_mode,omitempty"`
+	ToggleLanguage         string `json:"toggle_language,omitempty"`
+	ModelLanguageDetection bool   `json:"model_language_detection,omitempty"`
+
+	// Accelerator for each hotkey action, e.g. "translate": "ctrl+alt+h"
+	Hotkeys map[string]string `json:"hotkeys"`
+
+	// Translation backend: gemini, openai, ollama, deepl or libretranslate
+	Provider             string `json:"provider"`
+	GeminiBaseURL        string `json:"gemini_base_url,omitempty"` // Proxy or stand-in for the Gemini API
+	OpenAIAPIKey         string `json:"openai_api_key,omitempty"`
+	OpenAIBaseURL        string `json:"openai_base_url,omitempty"` // Any OpenAI-compatible endpoint
+	OllamaBaseURL        string `json:"ollama_base_url,omitempty"`
+	DeepLAPIKey          string `json:"deepl_api_key,omitempty"`
+	DeepLBaseURL         string `json:"deepl_base_url,omitempty"`
+	LibreTranslateURL    string `json:"libretranslate_url,omitempty"`
+	LibreTranslateAPIKey string `json:"libretranslate_api_key,omitempty"`
+
+	// Style of the LLM prompts: improved, faithful, casual, formal, technical or a style from the prompts directory
+	PromptStyle string `json:"prompt_style,omitempty"`
+	// Action being run, set on the config snapshot of an action to pick its prompt templates, never saved
+	PromptAction string `json:"-"`
+
+	// Actions hotkeys are bound to, they replace built-in actions with the same name
+	Actions []Action `json:"actions,omitempty"`
+
+	// Saved setups to switch between, ActiveProfile follows changes made while it is selected
+	Profiles      []Profile `json:"profiles,omitempty"`
+	ActiveProfile string    `json:"active_profile,omitempty"`
+
+	// Local HTTP API for editors and browser extensions, it listens on 127.0.0.1 only and every
+	// request needs the token. APIPort 0 uses the default of 8765.
+	APIEnabled bool   `json:"api_enabled,omitempty"`
+	APIPort    int    `json:"api_port,omitempty"`
+	APIToken   string `json:"api_token,omitempty"`
+
+	// API keys live in the credential store, config.json only keeps references like "keychain:gemini".
+	// CredentialStore picks the store for new keys: keychain, secret-service or file.
+	CredentialRefs  map[string]string `json:"credential_refs,omitempty"`
+	CredentialStore string            `json:"credential_store,omitempty"`
+
+	// Cached result of the Gemini models.list API
+	GeminiModels          []string  `json:"gemini_models,omitempty"`
+	GeminiModelsFetchedAt time.Time `json:"gemini_models_fetched_at,omitzero"`
 }
 
-// Global config variable
-var appConfig Config
-
-// Global variable for selected output languages
-var selectedLanguages []string
-
-// Available Gemini models
+// Built-in Gemini models, used until the live list has been fetched
 var geminiModels = []string{
 	"gemini-2.0-flash-lite",
-	"gemini-1.5-flash",
-	"gemini-1.5-pro",
-	"gemini-1.0-pro",
+	"gemini-2.0-flash",
+	"gemini-2.5-flash-lite",
+	"gemini-2.5-flash",
+	"gemini-2.5-pro",
 }
 
+// How long typing in the API key field has to pause before the key is saved
+const apiKeySaveDelay = time.Second
+
 // Function to update selected languages
-func updateSelectedLanguages() {
-	fmt.Printf("🌐 Selected output languages: %v\n", selectedLanguages)
-	// Update appConfig and save
-	appConfig.SelectedLanguages = selectedLanguages
-	if err := saveConfig(appConfig); err != nil {
-		fmt.Printf("❌ Error saving selected languages: %v\n", err)
+func updateSelectedLanguages(change func(selected []string) []string) {
+	err := updateConfig(func(config *Config) {
+		config.SelectedLanguages = change(config.SelectedLanguages)
+	})
+	if err != nil {
+		logf("❌ Error saving selected languages: %v\n", err)
 	} else {
-		fmt.Printf("✅ Selected languages saved to config\n")
+		logf("✅ Selected languages saved to config: %v\n", currentConfig().SelectedLanguages)
 	}
 }
 
@@ -79,62 +138,48 @@ func removeFromSlice(slice []string, item string) []string {
 	return result
 }
 
-// Get config file path (same directory as executable)
-func getConfigPath() string {
-	execPath, err := os.Executable()
-	if err != nil {
-		fmt.Printf("⚠️ Cannot get executable path, using current directory: %v\n", err)
-		return "config.json"
-	}
-	execDir := filepath.Dir(execPath)
-	configPath := filepath.Join(execDir, "config.json")
-	fmt.Printf("🔍 DEBUG: Executable path: %s\n", execPath)
-	fmt.Printf("🔍 DEBUG: Executable directory: %s\n", execDir)
-	fmt.Printf("🔍 DEBUG: Config path: %s\n", configPath)
-	return configPath
+// Load config from file and hide its API keys from log output
+func loadConfig() Config {
+	config := readConfig()
+	setSecrets(config)
+	return config
 }
 
-// Load config from file
-func loadConfig() Config {
+// Read config from file, .env or environment variables
+func readConfig() Config {
 	configPath := getConfigPath()
 
 	// Try to load from config.json first
-	if data, err := os.ReadFile(configPath); err == nil {
-		var config Config
-		if json.Unmarshal(data, &config) == nil {
-			// Set default model if not specified
-			if config.Model == "" {
-				config.Model = "gemini-2.0-flash-lite"
-			}
-			// Set default selected languages if not specified
-			if config.SelectedLanguages == nil {
-				config.SelectedLanguages = []string{"EN"} // Default to English only
-			}
-			// Set default include prefix if not specified
-			// includePrefix defaults to false (zero value)
-			// Set default G language if not specified
-			if config.GLanguage == "" {
-				config.GLanguage = "VN" // Default to Vietnamese
-			}
-			fmt.Printf("✅ Loaded config from: %s\n", configPath)
-			fmt.Printf("🌐 Loaded selected languages: %v\n", config.SelectedLanguages)
-			fmt.Printf("️ Include prefix: %v\n", config.IncludePrefix)
-			fmt.Printf("🎯 G hotkey language: %s\n", config.GLanguage)
+	config, err := readConfigFile(configPath)
+	if err == nil {
+		return config
+	}
+
+	// A broken file is replaced by its backup instead of being overwritten with defaults on the next save
+	var parseErr *configParseError
+	if errors.As(err, &parseErr) {
+		logf("❌ %v\n", err)
+		reportConfigError(recoverConfigFile(configPath, err))
+		if config, err := readConfigFile(configPath); err == nil {
 			return config
 		}
 	}
 
 	// Fallback to .env file (for development)
-	err := godotenv.Load()
+	err = godotenv.Load()
 	if err == nil {
 		apiKey := os.Getenv("GEMINI_API_KEY")
 		if apiKey != "" {
-			fmt.Println("✅ Loaded API key from .env file")
+			logln("✅ Loaded API key from .env file")
 			return Config{
+				Version:           configVersion,
 				GeminiAPIKey:      apiKey,
 				Model:             "gemini-2.0-flash-lite",
-				SelectedLanguages: []string{"EN"}, // Default to English only
+				SelectedLanguages: []string{"en"}, // Default to English only
 				IncludePrefix:     false,          // Default to false
+				ClipboardLanguage: "vi",           // Default to Vietnamese
+				Provider:          providerGemini,
+				Hotkeys:           defaultHotkeys(),
 			}
 		}
 	}
@@ -142,98 +187,139 @@ func loadConfig() Config {
 	// Fallback to environment variable
 	apiKey := os.Getenv("GEMINI_API_KEY")
 	if apiKey != "" {
-		fmt.Println("✅ Loaded API key from environment variable")
+		logln("✅ Loaded API key from environment variable")
 		return Config{
+			Version:           configVersion,
 			GeminiAPIKey:      apiKey,
 			Model:             "gemini-2.0-flash-lite",
-			SelectedLanguages: []string{"EN"}, // Default to English only
+			SelectedLanguages: []string{"en"}, // Default to English only
 			IncludePrefix:     false,          // Default to false
-			GLanguage:         "VN",           // Default to Vietnamese
+			ClipboardLanguage: "vi",           // Default to Vietnamese
+			Provider:          providerGemini,
+			Hotkeys:           defaultHotkeys(),
 		}
 	}
 
-	fmt.Println("⚠️  No API key found in config.json, .env, or environment variables")
+	logln("⚠️  No API key found in config.json, .env, or environment variables")
 	return Config{
+		Version:           configVersion,
 		GeminiAPIKey:      "",
 		Model:             "gemini-2.0-flash-lite",
-		SelectedLanguages: []string{"EN"}, // Default to English only
+		SelectedLanguages: []string{"en"}, // Default to English only
 		IncludePrefix:     false,          // Default to false
-		GLanguage:         "VN",           // Default to Vietnamese
+		ClipboardLanguage: "vi",           // Default to Vietnamese
+		Provider:          providerGemini,
+		Hotkeys:           defaultHotkeys(),
 	}
 }
 
-// Auto-start hotkey listener if API key is available
+// Auto-start hotkey listener if the config is valid
 func autoStartIfReady() bool {
-	if appConfig.GeminiAPIKey != "" && appConfig.GeminiAPIKey != "YOUR_GEMINI_API_KEY_HERE" {
-		fmt.Println("🚀 Auto-starting hotkey listener (API key found)")
-		go startHotkeyListener()
-		return true
+	config := currentConfig()
+	if errs := config.Validate(); errs != nil {
+		logf("⚠️ Not starting hotkey listener, config is invalid:\n%v\n", errs)
+		return false
 	}
-	return false
+	logf("🚀 Auto-starting hotkey listener (%s is ready)\n", config.Provider)
+	go startHotkeyListener()
+	return true
+}
+
+// Read config.json and fill in defaults and API keys
+func readConfigFile(configPath string) (Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return Config{}, err
+	}
+	// Older files are upgraded to the current format
+	config, migrated, err := parseConfigData(data)
+	if err != nil {
+		return Config{}, &configParseError{Path: configPath, Err: err}
+	}
+
+	// Set default model if not specified
+	if config.Model == "" {
+		config.Model = "gemini-2.0-flash-lite"
+	}
+	// Set default selected languages if not specified
+	if config.SelectedLanguages == nil {
+		config.SelectedLanguages = []string{"en"} // Default to English only
+	}
+	// Set default include prefix if not specified
+	// includePrefix defaults to false (zero value)
+	// Set default clipboard language if not specified
+	if config.ClipboardLanguage == "" {
+		config.ClipboardLanguage = "vi" // Default to Vietnamese
+	}
+	// Show the selected languages in the UI
+	enableUsedLanguages(&config)
+	// Set default provider if not specified
+	if config.Provider == "" {
+		config.Provider = providerGemini
+	}
+	// Set default hotkeys for actions without one
+	applyDefaultHotkeys(&config)
+	// Read API keys from the credential store, moving plaintext keys from older configs there
+	if loadCredentials(&config) {
+		logln("🔐 Moved API keys from config file to the credential store")
+		migrated = true
+	}
+	if migrated {
+		// Saving also backs up the migrated file, the old one may contain plaintext API keys
+		if err := saveConfig(config); err != nil {
+			logf("❌ Error saving migrated config: %v\n", err)
+		}
+	} else {
+		backupConfigFile(configPath, data)
+	}
+	logf("✅ Loaded config from: %s\n", configPath)
+	logf("🌐 Loaded selected languages: %v\n", config.SelectedLanguages)
+	logf("️ Include prefix: %v\n", config.IncludePrefix)
+	logf("🎯 Clipboard language: %s\n", config.ClipboardLanguage)
+	return config, nil
 }
 
 // Save config to file
 func saveConfig(config Config) error {
+	// The API key may have changed, keep it out of the log
+	setSecrets(config)
+
 	configPath := getConfigPath()
-	fmt.Printf("🔍 DEBUG: Saving config to: %s\n", configPath)
-	fmt.Printf(" DEBUG: Config data: %+v\n", config)
 
 	// Check if directory exists, create if not
 	dir := filepath.Dir(configPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		fmt.Printf("❌ Error creating directory %s: %v\n", dir, err)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logf("❌ Error creating directory %s: %v\n", dir, err)
 		return err
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	// Only references to the API keys are written to the file
+	config.Version = configVersion
+	data, err := json.MarshalIndent(storeCredentials(config), "", "  ")
 	if err != nil {
-		fmt.Printf("❌ Error marshaling config: %v\n", err)
+		logf("❌ Error marshaling config: %v\n", err)
 		return err
 	}
 
-	fmt.Printf("🔍 DEBUG: JSON data: %s\n", string(data))
-
-	// Try to write file
-	err = os.WriteFile(configPath, data, 0644)
+	// Write through a temporary file so a crash can't leave a half-written config, readable by the user only
+	noteConfigWritten(data)
+	err = writeFileAtomic(configPath, data, 0600)
 	if err != nil {
-		fmt.Printf("❌ Error writing config file: %v\n", err)
-		fmt.Printf("🔍 DEBUG: Current working directory: %s\n", func() string {
-			wd, _ := os.Getwd()
-			return wd
-		}())
+		logf("❌ Error writing config file: %v\n", err)
 		return err
 	}
 
 	// Verify file was written
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		fmt.Printf("❌ File was not created: %s\n", configPath)
+		logf("❌ File was not created: %s\n", configPath)
 		return fmt.Errorf("file was not created")
 	}
 
-	fmt.Printf("✅ Config saved successfully to: %s\n", configPath)
+	backupConfigFile(configPath, data)
+	logf("✅ Config saved successfully to: %s\n", configPath)
 	return nil
 }
 
-// Get Gemini API key from config (load from file each time)
-func getGeminiAPIKey() string {
-	// Load config from file each time instead of using global variable
-	config := loadConfig()
-	if config.GeminiAPIKey == "" {
-		return "YOUR_GEMINI_API_KEY_HERE"
-	}
-	return config.GeminiAPIKey
-}
-
-// Get Gemini model from config (load from file each time)
-func getGeminiModel() string {
-	// Load config from file each time instead of using global variable
-	config := loadConfig()
-	if config.Model == "" {
-		return "gemini-2.0-flash-lite"
-	}
-	return config.Model
-}
-
 // Request struct for Gemini API
 type GeminiRequest struct {
 	Contents []struct {
@@ -241,6 +327,13 @@ type GeminiRequest struct {
 			Text string `json:"text"`
 		} `json:"parts"`
 	} `json:"contents"`
+	GenerationConfig *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// Generation config for Gemini API, used to request structured JSON output
+type GeminiGenerationConfig struct {
+	ResponseMimeType string `json:"responseMimeType,omitempty"`
+	ResponseSchema   any    `json:"responseSchema,omitempty"`
 }
 
 // Response struct for Gemini API
@@ -254,10 +347,8 @@ type GeminiResponse struct {
 	} `json:"candidates"`
 }
 
-// Global channels for translation requests
-var translationChan = make(chan bool, 1)
-var dualTranslationChan = make(chan bool, 1)
-var gHotkeyTranslationChan = make(chan bool, 1)
+// Global channel for action requests, names of the actions to run
+var actionRequests = make(chan string, 4)
 
 type smallTheme struct {
 	fyne.Theme
@@ -301,7 +392,7 @@ func checkAccessibilityPermission() bool {
 
 	output, err := cmd.Output()
 	if err != nil {
-		fmt.Printf("❌ Error checking accessibility permission: %v\n", err)
+		logf("❌ Error checking accessibility permission: %v\n", err)
 		return false
 	}
 
@@ -310,12 +401,49 @@ func checkAccessibilityPermission() bool {
 }
 
 func main() {
-	// Load config at startup
-	appConfig = loadConfig()
+	// Read --config before anything touches the config file
+	parseFlags()
 
-	// Initialize selectedLanguages from config
-	selectedLanguages = appConfig.SelectedLanguages
-	fmt.Printf("🌐 Initialized selected languages: %v\n", selectedLanguages)
+	// Subcommands such as translate and config run without the window
+	if code, handled := runSubcommand(flag.Args()); handled {
+		os.Exit(code)
+	}
+
+	// A second launch shows the window of the running instance, two hotkey listeners
+	// would translate and paste everything twice
+	instanceListener, err := acquireInstanceLock()
+	if errors.Is(err, errAlreadyRunning) {
+		if _, err := sendToInstance(instanceShow); err != nil {
+			logf("❌ Already running, cannot show its window: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		logln("👋 Already running, showed its window")
+		os.Exit(exitOK)
+	} else if err != nil {
+		logf("⚠️ Cannot check for a running instance: %v\n", err)
+	}
+
+	// Load config at startup and apply later edits of the file
+	setConfig(loadConfig())
+	go watchConfigFile()
+
+	// Check the config before anything uses it
+	if errs := currentConfig().Validate(); errs != nil {
+		logf("⚠️ Config has problems:\n%v\n", errs)
+	}
+
+	// Apply changed hotkeys to the running listener
+	subscribeHotkeyChanges()
+
+	// Serve the local API for editor and browser integrations when enabled
+	subscribeAPIServer()
+
+	// Pick clipboard and keystroke tools for this desktop
+	if err := initPlatform(); err != nil {
+		logf("⚠️ %v\n", err)
+	}
+
+	logf("🌐 Initialized selected languages: %v\n", currentConfig().SelectedLanguages)
 
 	myApp := app.New()
 	myApp.Settings().SetTheme(&smallTheme{theme.DefaultTheme()})
@@ -325,7 +453,7 @@ func main() {
 	// Check accessibility permission first
 	if !checkAccessibilityPermission() {
 		myApp.Settings().SetTheme(&defaultTheme{theme.DefaultTheme()})
-		fmt.Println("⚠️ Accessibility permission not granted")
+		logln("⚠️ Accessibility permission not granted")
 		// Show instruction dialog with detailed guidance
 		instructionText := `🔒 Accessibility Permission Required
 
@@ -359,7 +487,7 @@ The app will appear in the list once you add it.`
 		myWindow.ShowAndRun()
 		return
 	} else {
-		fmt.Println("✅ Accessibility permission granted")
+		logln("✅ Accessibility permission granted")
 	}
 
 	// Auto-start if API key is available
@@ -374,50 +502,217 @@ The app will appear in the list once you add it.`
 	subtitleLabel.Alignment = fyne.TextAlignCenter
 
 	// Create API key section
-	apiKeyLabel := widget.NewLabel("🔑 Gemini API Key")
+	apiKeyLabel := widget.NewLabel("🔑 API Key")
 	apiKeyLabel.TextStyle = fyne.TextStyle{Bold: true}
 
 	apiKeyEntry := widget.NewEntry()
-	apiKeyEntry.SetText(appConfig.GeminiAPIKey)
-	apiKeyEntry.SetPlaceHolder("Enter your Gemini API key...")
+	apiKeyEntry.SetText(providerAPIKey(currentConfig()))
+	apiKeyEntry.SetPlaceHolder("Enter your API key...")
 	// apiKeyEntry.Password = true // Hide API key for security
 
-	// Auto-save when API key changes
-	apiKeyEntry.OnChanged = func(text string) {
-		fmt.Printf("🔍 DEBUG: API key changed to: %s\n", text)
-		appConfig.GeminiAPIKey = text
-		if err := saveConfig(appConfig); err != nil {
-			fmt.Printf("❌ Error auto-saving config: %v\n", err)
+	// Save the API key of a provider, the provider may have been switched since the key was typed
+	saveAPIKey := func(provider string, text string) {
+		err := updateConfig(func(config *Config) {
+			if config.Provider == provider {
+				setProviderAPIKey(config, text)
+			}
+		})
+		if err != nil {
+			logf("❌ Error auto-saving config: %v\n", err)
 		} else {
-			fmt.Printf("✅ API key auto-saved successfully\n")
+			logf("✅ API key auto-saved successfully\n")
+		}
+	}
+
+	// Auto-save when typing pauses, every save may write to the keychain and show a prompt
+	var apiKeySaveTimer *time.Timer
+	var apiKeyProvider string
+	apiKeyEntry.OnChanged = func(text string) {
+		if apiKeySaveTimer != nil {
+			apiKeySaveTimer.Stop()
+		}
+		provider := currentConfig().Provider
+		apiKeyProvider = provider
+		apiKeySaveTimer = time.AfterFunc(apiKeySaveDelay, func() { saveAPIKey(provider, text) })
+	}
+
+	// Save a key still waiting for typing to pause right away
+	flushAPIKey := func() {
+		if apiKeySaveTimer != nil && apiKeySaveTimer.Stop() {
+			saveAPIKey(apiKeyProvider, apiKeyEntry.Text)
 		}
 	}
 
 	// Auto-save when API key field is submitted (Enter key pressed)
 	apiKeyEntry.OnSubmitted = func(text string) {
-		fmt.Printf("🔍 DEBUG: API key submitted: %s\n", text)
-		appConfig.GeminiAPIKey = text
-		if err := saveConfig(appConfig); err != nil {
-			fmt.Printf("❌ Error auto-saving config on submit: %v\n", err)
-		} else {
-			fmt.Printf("✅ API key auto-saved on submit\n")
-		}
+		flushAPIKey()
 	}
 
 	// Create model selection section
 	modelLabel := widget.NewLabel("🤖 AI Model")
 	modelLabel.TextStyle = fyne.TextStyle{Bold: true}
 
-	modelSelect := widget.NewSelect(geminiModels, func(value string) {
-		appConfig.Model = value
+	modelSelect := widget.NewSelect(modelsForProvider(currentConfig().Provider), func(value string) {
 		// Auto-save when model changes
-		if err := saveConfig(appConfig); err != nil {
-			fmt.Printf("❌ Error auto-saving config: %v\n", err)
+		err := updateConfig(func(config *Config) { config.Model = value })
+		if err != nil {
+			logf("❌ Error auto-saving config: %v\n", err)
 		} else {
-			fmt.Printf("✅ Model auto-saved\n")
+			logf("✅ Model auto-saved\n")
+		}
+	})
+	modelSelect.SetSelected(currentConfig().Model)
+
+	// Show the model list and API key of a provider
+	showProvider := func(config Config) {
+		models := modelsForProvider(config.Provider)
+		if !slices.Equal(modelSelect.Options, models) {
+			modelSelect.SetOptions(models)
+		}
+		// Don't overwrite the key while it is being typed
+		if text := providerAPIKey(config); apiKeyEntry.Text != text && myWindow.Canvas().Focused() != apiKeyEntry {
+			apiKeyEntry.SetText(text)
 		}
+		if providerRequiresAPIKey(config.Provider) {
+			apiKeyEntry.Enable()
+		} else {
+			apiKeyEntry.Disable()
+		}
+	}
+
+	// Create provider selection, switching provider swaps the model list and API key
+	providerSelect := widget.NewSelect(translationProviders, func(value string) {
+		// The key being typed belongs to the previous provider
+		flushAPIKey()
+		err := updateConfig(func(config *Config) {
+			if config.Provider == value {
+				return
+			}
+			logf("🔌 Translation provider changed to: %s\n", value)
+			config.Provider = value
+			if models := modelsForProvider(value); !contains(models, config.Model) {
+				config.Model = models[0]
+			}
+		})
+		if err != nil {
+			logf("❌ Error auto-saving config: %v\n", err)
+		}
+	})
+	providerSelect.SetSelected(currentConfig().Provider)
+	showProvider(currentConfig())
+
+	// Reload the Gemini model list from the API and update the dropdown
+	refreshModels := func(showErrors bool) {
+		models, err := refreshGeminiModels()
+		fyne.Do(func() {
+			if err != nil && showErrors {
+				dialog.ShowError(fmt.Errorf("could not fetch models, using the cached list: %v", err), myWindow)
+			}
+			config := currentConfig()
+			if config.Provider != providerGemini {
+				return
+			}
+			modelSelect.SetOptions(models)
+			if !contains(models, config.Model) && len(models) > 0 {
+				modelSelect.SetSelected(models[0])
+			}
+		})
+	}
+
+	refreshModelsButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
+		if currentConfig().Provider != providerGemini {
+			dialog.ShowInformation("🤖 AI Model", "Fetching the model list is only available for Gemini.", myWindow)
+			return
+		}
+		go refreshModels(true)
+	})
+
+	// Refresh a stale model list in the background
+	if config := currentConfig(); config.Provider == providerGemini && hasUsableAPIKey(config) && geminiModelsCacheExpired(config) {
+		go refreshModels(false)
+	}
+
+	// Create prompt style selection
+	promptStyleLabel := widget.NewLabel("✍️ Prompt Style")
+	promptStyleLabel.TextStyle = fyne.TextStyle{Bold: true}
+	promptStyle := func(config Config) string {
+		if config.PromptStyle == "" {
+			return promptStyleImproved
+		}
+		return config.PromptStyle
+	}
+	promptStyleSelect := widget.NewSelect(promptStyleNames(), func(value string) {
+		if err := updateConfig(func(config *Config) { config.PromptStyle = value }); err != nil {
+			logf("❌ Error saving prompt style: %v\n", err)
+		} else {
+			logf("✅ Prompt style saved: %s\n", value)
+		}
+	})
+	promptStyleSelect.Selected = promptStyle(currentConfig())
+
+	// Create profile selection, picking a profile applies its settings
+	profileLabel := widget.NewLabel("👤 Profile")
+	profileLabel.TextStyle = fyne.TextStyle{Bold: true}
+	profileSelect := widget.NewSelect(profileNames(currentConfig()), func(value string) {
+		if value == "" || strings.EqualFold(value, currentConfig().ActiveProfile) {
+			return
+		}
+		if err := activateProfile(value); err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+	})
+	profileSelect.PlaceHolder = "No profile"
+	profileSelect.Selected = currentConfig().ActiveProfile
+
+	// Save the current settings as a new profile, or over the active one
+	saveProfileButton := widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), func() {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("e.g. Customer email")
+		nameEntry.SetText(currentConfig().ActiveProfile)
+		items := []*widget.FormItem{widget.NewFormItem("Name", nameEntry)}
+		dialog.ShowForm("💾 Save Profile", "Save", "Cancel", items, func(ok bool) {
+			if !ok {
+				return
+			}
+			var saveErr error
+			err := updateConfig(func(config *Config) { saveErr = saveProfile(config, nameEntry.Text) })
+			if saveErr != nil {
+				dialog.ShowError(saveErr, myWindow)
+				return
+			}
+			if err != nil {
+				logf("❌ Error saving profile: %v\n", err)
+			} else {
+				logf("✅ Profile saved: %s\n", nameEntry.Text)
+			}
+		}, myWindow)
+	})
+	saveProfileButton.Importance = widget.LowImportance
+
+	deleteProfileButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		name := currentConfig().ActiveProfile
+		if name == "" {
+			dialog.ShowInformation("👤 Profile", "Select the profile to delete first.", myWindow)
+			return
+		}
+		dialog.ShowConfirm("🗑️ Delete Profile", fmt.Sprintf("Delete profile %q? The current settings stay as they are.", name), func(ok bool) {
+			if !ok {
+				return
+			}
+			var deleteErr error
+			err := updateConfig(func(config *Config) { deleteErr = deleteProfile(config, name) })
+			if deleteErr != nil {
+				dialog.ShowError(deleteErr, myWindow)
+				return
+			}
+			if err != nil {
+				logf("❌ Error deleting profile: %v\n", err)
+			} else {
+				logf("✅ Profile deleted: %s\n", name)
+			}
+		}, myWindow)
 	})
-	modelSelect.SetSelected(appConfig.Model)
+	deleteProfileButton.Importance = widget.LowImportance
 
 	// Remove the OnFocusChanged for modelSelect since it doesn't exist
 	// The OnChanged callback in NewSelect is sufficient for auto-saving
@@ -426,18 +721,19 @@ The app will appear in the list once you add it.`
 	var startButton *widget.Button
 	startButton = widget.NewButton("🚀 Start Hotkey Listener", func() {
 		// Update config before starting
-		appConfig.GeminiAPIKey = apiKeyEntry.Text
-		appConfig.Model = modelSelect.Selected
-
-		// Validate API key
-		if appConfig.GeminiAPIKey == "" || appConfig.GeminiAPIKey == "YOUR_GEMINI_API_KEY_HERE" {
-			dialog.ShowInformation("⚠️ Configuration Required", "Please enter a valid Gemini API key before starting!", myWindow)
-			return
+		flushAPIKey()
+		err := updateConfig(func(config *Config) {
+			setProviderAPIKey(config, apiKeyEntry.Text)
+			config.Model = modelSelect.Selected
+		})
+		if err != nil {
+			logf("❌ Error saving config: %v\n", err)
 		}
 
-		// Save config
-		if err := saveConfig(appConfig); err != nil {
-			fmt.Printf("❌ Error saving config: %v\n", err)
+		// Refuse to start with a config that would fail at hotkey time
+		if errs := currentConfig().Validate(); errs != nil {
+			dialog.ShowInformation("⚠️ Configuration Required", fmt.Sprintf("Please fix these settings before starting:\n\n%v", errs), myWindow)
+			return
 		}
 
 		go startHotkeyListener()
@@ -462,7 +758,80 @@ The app will appear in the list once you add it.`
 	instructionsLabel.TextStyle = fyne.TextStyle{Bold: true}
 	// instructionsLabel.Alignment = fyne.TextLe
 
-	hotkeyHLabel := widget.NewLabel("⌨️  Control + Option + H: Translate selected text to English only \n⌨️  Control + Option + J: Select all text and translate to English + Japanese\n⌨️  Control + Option + G: Translate clipboard content to selected language (copies to clipboard & shows alert)")
+	// One row per action with its current hotkey and a button to record a new one
+	hotkeyRows := container.NewVBox()
+	hotkeyLabels := map[string]*widget.Label{}
+	var hotkeyRowActions []string
+	var outputLanguageLabel, gLanguageLabel *widget.Label
+	var recordHotkey func(action string)
+
+	// Refresh every label that shows a hotkey, the rows are rebuilt when actions were added or removed
+	updateHotkeyLabels := func(config Config) {
+		if names := actionNames(config); !slices.Equal(names, hotkeyRowActions) {
+			hotkeyRowActions = names
+			hotkeyRows.RemoveAll()
+			clear(hotkeyLabels)
+			for _, action := range names {
+				label := widget.NewLabel("")
+				hotkeyLabels[action] = label
+				recordButton := widget.NewButton("Record", func() {
+					recordHotkey(action)
+				})
+				recordButton.Importance = widget.LowImportance
+				hotkeyRows.Add(container.NewBorder(nil, nil, nil, recordButton, label))
+			}
+		}
+		for action, label := range hotkeyLabels {
+			label.SetText(fmt.Sprintf("⌨️  %s: %s", hotkeyLabel(config, action), actionDescription(config, action)))
+		}
+		outputLanguageLabel.SetText(hotkeyLabel(config, actionDualTranslate) + " Language:")
+		gLanguageLabel.SetText(hotkeyLabel(config, actionClipboardTranslate) + " language:")
+	}
+
+	// Record the next key press as the hotkey of an action
+	recordHotkey = func(action string) {
+		if !hotkeyListenerRunning.Load() {
+			dialog.ShowInformation("⌨️ Record Hotkey", "Start the hotkey listener before recording a new hotkey.", myWindow)
+			return
+		}
+
+		recorded := recordNextHotkey()
+		recordDialog := dialog.NewCustom("⌨️ Record Hotkey", "Cancel",
+			widget.NewLabel(fmt.Sprintf("Press the new hotkey for:\n%s", actionDescription(currentConfig(), action))), myWindow)
+		recordDialog.SetOnClosed(cancelHotkeyRecording)
+		recordDialog.Show()
+
+		go func() {
+			hotkey, ok := <-recorded
+			if !ok {
+				return // Recording was cancelled
+			}
+			fyne.Do(func() {
+				recordDialog.Hide()
+				if hotkey.Modifiers == 0 {
+					dialog.ShowError(fmt.Errorf("%s: at least one modifier is required", hotkey.Label()), myWindow)
+					return
+				}
+
+				hotkeys := currentConfig().Hotkeys
+				if hotkeys == nil {
+					hotkeys = map[string]string{}
+				}
+				hotkeys[action] = hotkey.String()
+
+				// Reject bindings that conflict with another action
+				if err := setActiveHotkeys(hotkeys); err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				if err := updateConfig(func(config *Config) { config.Hotkeys = hotkeys }); err != nil {
+					logf("❌ Error saving hotkeys: %v\n", err)
+				} else {
+					logf("✅ Hotkey for %s saved: %s\n", action, hotkey)
+				}
+			})
+		}()
+	}
 
 	// Create warning section
 	warningLabel := widget.NewLabel("⚠️  Important")
@@ -479,72 +848,127 @@ The app will appear in the list once you add it.`
 	warningTextButton.Importance = widget.LowImportance
 	// warningTextButton.Alignment = fyne.TextAlignCenter
 
+	// Validation errors are shown in a label under the widget of the field
+	type fieldErrorWidget struct {
+		fields []string
+		label  *widget.Label
+	}
+	var fieldErrorWidgets []fieldErrorWidget
+	fieldErrorLabel := func(fields ...string) *widget.Label {
+		label := widget.NewLabel("")
+		label.Importance = widget.DangerImportance
+		label.Wrapping = fyne.TextWrapWord
+		label.Hide()
+		fieldErrorWidgets = append(fieldErrorWidgets, fieldErrorWidget{fields: fields, label: label})
+		return label
+	}
+
 	// Create main content layout
+	providerLabel := widget.NewLabel("🔌 Provider")
+	providerLabel.TextStyle = fyne.TextStyle{Bold: true}
+
 	configSection := container.NewVBox(
+		profileLabel,
+		container.NewBorder(nil, nil, nil, container.NewHBox(saveProfileButton, deleteProfileButton), profileSelect),
+		fieldErrorLabel("profiles", "active_profile"),
+		providerLabel,
+		providerSelect,
+		fieldErrorLabel("provider"),
 		apiKeyLabel,
 		apiKeyEntry,
+		fieldErrorLabel("gemini_api_key", "openai_api_key", "deepl_api_key", "libretranslate_api_key"),
 		// widget.NewLabel(""), // Spacer
 		modelLabel,
-		modelSelect,
+		container.NewBorder(nil, nil, nil, refreshModelsButton, modelSelect),
+		fieldErrorLabel("model"),
+		promptStyleLabel,
+		promptStyleSelect,
+		fieldErrorLabel("prompt_style"),
 	)
 
-	// tạo 1 selection gồm có 3 checkbox [EN] [VN] [JP]
-	outputLanguageLabel := widget.NewLabel("Control + Option + J Language:")
+	// tạo danh sách checkbox ngôn ngữ từ registry
+	outputLanguageLabel = widget.NewLabel("")
 	outputLanguageLabel.TextStyle = fyne.TextStyle{Bold: true}
 
-	// Create individual checkboxes for each language
-	enCheck := widget.NewCheck("EN", func(value bool) {
-		if value {
-			// Add to selected languages if not already present
-			if !contains(selectedLanguages, "EN") {
-				selectedLanguages = append(selectedLanguages, "EN")
-			}
+	// Create a checkbox for each enabled language
+	languageChecks := container.NewHBox()
+	updateLanguageChecks := func(config Config) {
+		languageChecks.RemoveAll()
+		for _, language := range enabledLanguages(config) {
+			code := language.Code
+			check := widget.NewCheck(language.Label, func(value bool) {
+				updateSelectedLanguages(func(selected []string) []string {
+					if value {
+						// Add to selected languages if not already present
+						if !contains(selected, code) {
+							selected = append(selected, code)
+						}
+						return selected
+					}
+					// Remove from selected languages
+					return removeFromSlice(selected, code)
+				})
+			})
+			check.Checked = contains(config.SelectedLanguages, code)
+			languageChecks.Add(check)
+		}
+	}
+
+	languageSelection := container.NewHBox(
+		outputLanguageLabel,
+		languageChecks,
+	)
+	// Create prefix checkbox
+	prefixCheck := widget.NewCheck("Include [LANG] prefix, ex: [EN]: text_text", func(value bool) {
+		if err := updateConfig(func(config *Config) { config.IncludePrefix = value }); err != nil {
+			logf("❌ Error saving prefix setting: %v\n", err)
 		} else {
-			// Remove from selected languages
-			selectedLanguages = removeFromSlice(selectedLanguages, "EN")
+			logf("✅ Prefix setting saved: %v\n", value)
 		}
-		updateSelectedLanguages()
 	})
-	enCheck.SetChecked(contains(appConfig.SelectedLanguages, "EN"))
+	prefixCheck.SetChecked(currentConfig().IncludePrefix)
 
-	vnCheck := widget.NewCheck("VN", func(value bool) {
-		if value {
-			if !contains(selectedLanguages, "VN") {
-				selectedLanguages = append(selectedLanguages, "VN")
-			}
+	// Create clipboard checkbox
+	keepClipboardCheck := widget.NewCheck("Keep translation in clipboard (don't restore previous content)", func(value bool) {
+		if err := updateConfig(func(config *Config) { config.KeepTranslationInClipboard = value }); err != nil {
+			logf("❌ Error saving clipboard setting: %v\n", err)
 		} else {
-			selectedLanguages = removeFromSlice(selectedLanguages, "VN")
+			logf("✅ Clipboard setting saved: %v\n", value)
 		}
-		updateSelectedLanguages()
 	})
-	vnCheck.SetChecked(contains(appConfig.SelectedLanguages, "VN"))
-
-	jpCheck := widget.NewCheck("JP", func(value bool) {
-		if value {
-			if !contains(selectedLanguages, "JP") {
-				selectedLanguages = append(selectedLanguages, "JP")
-			}
+	keepClipboardCheck.SetChecked(currentConfig().KeepTranslationInClipboard)
+
+	// Create source language detection settings
+	sameLanguageLabel := widget.NewLabel("Already in target language:")
+	sameLanguageLabel.TextStyle = fyne.TextStyle{Bold: true}
+	sameLanguageSelect := widget.NewSelect(sameLanguageModes, func(value string) {
+		if err := updateConfig(func(config *Config) { config.SameLanguageMode = value }); err != nil {
+			logf("❌ Error saving same language setting: %v\n", err)
 		} else {
-			selectedLanguages = removeFromSlice(selectedLanguages, "JP")
+			logf("✅ Same language setting saved: %s\n", value)
 		}
-		updateSelectedLanguages()
 	})
-	jpCheck.SetChecked(contains(appConfig.SelectedLanguages, "JP"))
-
-	languageSelection := container.NewHBox(
-		outputLanguageLabel,
-		container.NewHBox(enCheck, vnCheck, jpCheck),
-	)
-	// Create prefix checkbox
-	prefixCheck := widget.NewCheck("Include [LANG] prefix, ex: [EN]: text_text", func(value bool) {
-		appConfig.IncludePrefix = value
-		if err := saveConfig(appConfig); err != nil {
-			fmt.Printf("❌ Error saving prefix setting: %v\n", err)
+	sameLanguageMode := func(config Config) string {
+		if config.SameLanguageMode == "" {
+			return sameLanguageTranslate
+		}
+		return config.SameLanguageMode
+	}
+	sameLanguageSelect.Selected = sameLanguageMode(currentConfig())
+	modelDetectionCheck := widget.NewCheck("Confirm with the AI model when unsure", func(value bool) {
+		if err := updateConfig(func(config *Config) { config.ModelLanguageDetection = value }); err != nil {
+			logf("❌ Error saving language detection setting: %v\n", err)
 		} else {
-			fmt.Printf("✅ Prefix setting saved: %v\n", value)
+			logf("✅ Language detection setting saved: %v\n", value)
 		}
 	})
-	prefixCheck.SetChecked(appConfig.IncludePrefix)
+	modelDetectionCheck.SetChecked(currentConfig().ModelLanguageDetection)
+	sameLanguageSection := container.NewHBox(
+		sameLanguageLabel,
+		sameLanguageSelect,
+		modelDetectionCheck,
+	)
+
 	prefixIncludeLabel := widget.NewLabel("Prefix: ")
 	prefixIncludeLabel.TextStyle = fyne.TextStyle{Bold: true}
 	includePrefixSection := container.NewHBox(
@@ -553,32 +977,151 @@ The app will appear in the list once you add it.`
 	)
 
 	// Create G hotkey language selection with radio buttons
-	gLanguageLabel := widget.NewLabel("Control + Option + G language:")
+	gLanguageLabel = widget.NewLabel("")
 	gLanguageLabel.TextStyle = fyne.TextStyle{Bold: true}
 
-	// Create a single radio group for language selection
-	gLanguageOptions := []string{"EN", "JP", "VN"}
-	gLanguageRadio := widget.NewRadioGroup(gLanguageOptions, func(value string) {
-		appConfig.GLanguage = value
-		if err := saveConfig(appConfig); err != nil {
-			fmt.Printf("❌ Error saving G language setting: %v\n", err)
+	// Create a single radio group for language selection, options are the labels of the enabled languages
+	gLanguageCodes := map[string]string{}
+	gLanguageRadio := widget.NewRadioGroup(nil, func(value string) {
+		code, ok := gLanguageCodes[value]
+		if !ok || code == currentConfig().ClipboardLanguage {
+			return
+		}
+		if err := updateConfig(func(config *Config) { config.ClipboardLanguage = code }); err != nil {
+			logf("❌ Error saving G language setting: %v\n", err)
 		} else {
-			fmt.Printf("✅ G language setting saved: %s\n", value)
+			logf("✅ G language setting saved: %s\n", code)
 		}
 	})
 	gLanguageRadio.Horizontal = true
 
-	// Set the selected radio button based on current config
-	gLanguageRadio.SetSelected(appConfig.GLanguage)
+	updateClipboardLanguageRadio := func(config Config) {
+		var options []string
+		for _, language := range enabledLanguages(config) {
+			gLanguageCodes[language.Label] = language.Code
+			options = append(options, language.Label)
+		}
+		gLanguageRadio.Options = options
+		// Set the selected radio button based on current config
+		if language, ok := findLanguage(config, config.ClipboardLanguage); ok {
+			gLanguageRadio.Selected = language.Label
+		}
+		gLanguageRadio.Refresh()
+	}
+
+	updateLanguageChecks(currentConfig())
+	updateClipboardLanguageRadio(currentConfig())
+
+	// Add a language from the registry, or a custom one, to the checkboxes and the radio group
+	const otherLanguageOption = "Other..."
+	addLanguageButton := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+		addable := addableLanguages(currentConfig())
+		var options []string
+		for _, language := range addable {
+			options = append(options, language.DisplayName())
+		}
+		options = append(options, otherLanguageOption)
+
+		languageSelect := widget.NewSelect(options, nil)
+		codeEntry := widget.NewEntry()
+		codeEntry.SetPlaceHolder("BCP-47 code, e.g. pt-BR")
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("e.g. Brazilian Portuguese")
+		nativeNameEntry := widget.NewEntry()
+		nativeNameEntry.SetPlaceHolder("e.g. Português")
+
+		items := []*widget.FormItem{
+			widget.NewFormItem("Language", languageSelect),
+			widget.NewFormItem("Code", codeEntry),
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("Native name", nativeNameEntry),
+		}
+		items[1].HintText = "Only needed for other languages"
+
+		dialog.ShowForm("➕ Add Language", "Add", "Cancel", items, func(ok bool) {
+			if !ok {
+				return
+			}
+
+			var language Language
+			if index := languageSelect.SelectedIndex(); index >= 0 && index < len(addable) {
+				language = addable[index]
+			} else {
+				language = Language{Code: codeEntry.Text, Name: nameEntry.Text, NativeName: nativeNameEntry.Text}
+			}
+
+			var addErr error
+			err := updateConfig(func(config *Config) { addErr = addLanguage(config, language) })
+			if addErr != nil {
+				dialog.ShowError(addErr, myWindow)
+				return
+			}
+			if err != nil {
+				logf("❌ Error saving languages: %v\n", err)
+			} else {
+				logf("✅ Language added: %s\n", language.Code)
+			}
+		}, myWindow)
+	})
+	addLanguageButton.Importance = widget.LowImportance
+	languageSelection.Add(addLanguageButton)
 
 	gLanguageSection := container.NewHBox(
 		gLanguageLabel,
 		gLanguageRadio,
 	)
+	updateHotkeyLabels(currentConfig())
+
+	// Show problems with the config file, e.g. a manual edit that broke the JSON
+	subscribeConfigErrors(func(err error) {
+		fyne.Do(func() {
+			dialog.ShowError(err, myWindow)
+		})
+	})
+
+	// Show config changes made in the window, by the file watcher or from other goroutines
+	subscribeConfig(func(config Config) {
+		fyne.Do(func() {
+			if providerSelect.Selected != config.Provider {
+				providerSelect.SetSelected(config.Provider)
+			}
+			showProvider(config)
+			if modelSelect.Selected != config.Model {
+				modelSelect.SetSelected(config.Model)
+			}
+			if names := profileNames(config); !slices.Equal(profileSelect.Options, names) {
+				profileSelect.SetOptions(names)
+			}
+			if profileSelect.Selected != config.ActiveProfile {
+				// Set directly, SetSelected would switch the profile again
+				profileSelect.Selected = config.ActiveProfile
+				profileSelect.Refresh()
+			}
+			if style := promptStyle(config); promptStyleSelect.Selected != style {
+				promptStyleSelect.SetSelected(style)
+			}
+			prefixCheck.SetChecked(config.IncludePrefix)
+			keepClipboardCheck.SetChecked(config.KeepTranslationInClipboard)
+			if mode := sameLanguageMode(config); sameLanguageSelect.Selected != mode {
+				sameLanguageSelect.SetSelected(mode)
+			}
+			modelDetectionCheck.SetChecked(config.ModelLanguageDetection)
+			updateLanguageChecks(config)
+			updateClipboardLanguageRadio(config)
+			updateHotkeyLabels(config)
+		})
+	})
+
+	// Problems of fields that can only be changed in config.json
+	otherErrorsLabel := widget.NewLabel("")
+	otherErrorsLabel.Importance = widget.DangerImportance
+	otherErrorsLabel.Wrapping = fyne.TextWrapWord
+	otherErrorsLabel.Hide()
 
 	buttonSection := container.NewVBox(
 		widget.NewLabel(""), // Spacer
 		startButton,
+		otherErrorsLabel,
 		widget.NewLabel(""), // Spacer
 	)
 	// set width 100% for buttonSection
@@ -586,7 +1129,7 @@ The app will appear in the list once you add it.`
 	hotkeySection := container.NewVBox(
 		instructionsLabel,
 		// widget.NewLabel(""), // Spacer
-		hotkeyHLabel,
+		hotkeyRows,
 		// hotkeyHDesc,
 		// widget.NewLabel(""), // Spacer
 		// hotkeyJLabel,
@@ -613,18 +1156,94 @@ The app will appear in the list once you add it.`
 		buttonSection,
 		// widget.NewSeparator(),
 		languageSelection,
+		fieldErrorLabel("selected_languages", "custom_languages"),
 		includePrefixSection,
+		keepClipboardCheck,
+		sameLanguageSection,
+		fieldErrorLabel("same_language_mode", "toggle_language"),
 		gLanguageSection,
+		fieldErrorLabel("clipboard_language"),
 		widget.NewSeparator(),
 
 		// Hotkey instructions
 		hotkeySection,
+		fieldErrorLabel("hotkeys"),
 		widget.NewSeparator(),
 
 		// Warning
 		warningSection,
 	)
 
+	// Validate the config on load and after every change and show the errors next to the fields
+	showValidationErrors := func(config Config) {
+		errs := config.Validate()
+		shown := map[string]bool{}
+		for _, w := range fieldErrorWidgets {
+			var messages []string
+			for _, field := range w.fields {
+				if message := errs.For(field); message != "" {
+					messages = append(messages, message)
+				}
+				shown[field] = true
+			}
+			w.label.SetText("⚠️ " + strings.Join(messages, "; "))
+			if len(messages) > 0 {
+				w.label.Show()
+			} else {
+				w.label.Hide()
+			}
+		}
+
+		var others []string
+		for _, err := range errs {
+			if !shown[err.Field] {
+				others = append(others, "⚠️ "+err.Error())
+			}
+		}
+		otherErrorsLabel.SetText(strings.Join(others, "\n"))
+		if len(others) > 0 {
+			otherErrorsLabel.Show()
+		} else {
+			otherErrorsLabel.Hide()
+		}
+	}
+	showValidationErrors(currentConfig())
+	subscribeConfig(func(config Config) {
+		fyne.Do(func() { showValidationErrors(config) })
+	})
+
+	// Show the window and switch profiles from the system tray
+	if desk, ok := myApp.(desktop.App); ok {
+		var trayProfiles []string
+		trayActive := "-"
+		updateTrayMenu := func(config Config) {
+			names := profileNames(config)
+			if slices.Equal(names, trayProfiles) && config.ActiveProfile == trayActive {
+				return
+			}
+			trayProfiles, trayActive = names, config.ActiveProfile
+
+			items := []*fyne.MenuItem{fyne.NewMenuItem("Show Window", myWindow.Show)}
+			if len(names) > 0 {
+				items = append(items, fyne.NewMenuItemSeparator())
+			}
+			for _, name := range names {
+				item := fyne.NewMenuItem(name, func() {
+					if err := activateProfile(name); err != nil {
+						logf("❌ Error switching profile: %v\n", err)
+					}
+				})
+				item.Checked = strings.EqualFold(name, config.ActiveProfile)
+				items = append(items, item)
+			}
+			desk.SetSystemTrayMenu(fyne.NewMenu("Hotkey Translator", items...))
+		}
+		updateTrayMenu(currentConfig())
+		subscribeConfig(func(config Config) {
+			fyne.Do(func() { updateTrayMenu(config) })
+		})
+	}
+
 	// Set window properties
 	myWindow.SetContent(content)
 	myWindow.Resize(fyne.NewSize(300, 250))
@@ -634,478 +1253,162 @@ The app will appear in the list once you add it.`
 	// Start translation handler
 	go handleTranslationRequests()
 
+	// Let window manager shortcuts trigger translations over D-Bus
+	if runtime.GOOS == "linux" {
+		if stop, err := startDBusService(); err != nil {
+			logf("⚠️ D-Bus service not available: %v\n", err)
+		} else {
+			defer stop()
+		}
+	}
+
+	// Answer later launches
+	if instanceListener != nil {
+		defer instanceListener.Close()
+		go serveInstance(instanceListener, func() {
+			fyne.Do(func() {
+				myWindow.Show()
+				myWindow.RequestFocus()
+			})
+		})
+	}
+
 	myWindow.ShowAndRun()
 }
 
 // Handle translation requests
 func handleTranslationRequests() {
-	for {
-		select {
-		case <-translationChan:
-			fmt.Println("🎯 Translation request received (English only)")
-			// Use a goroutine with proper error handling
-			go func() {
-				defer func() {
-					if r := recover(); r != nil {
-						fmt.Printf("❌ Panic in translation: %v\n", r)
-					}
-				}()
-				performTranslation()
-			}()
-		case <-dualTranslationChan:
-			fmt.Println("🎯 Dual translation request received (English + Japanese)")
-			// Use a goroutine with proper error handling
-			go func() {
-				defer func() {
-					if r := recover(); r != nil {
-						fmt.Printf("❌ Panic in dual translation: %v\n", r)
-					}
-				}()
-				performDualTranslation()
-			}()
-		case <-gHotkeyTranslationChan:
-			fmt.Println("🎯 G hotkey translation request received")
-			// Use a goroutine with proper error handling
-			go func() {
-				defer func() {
-					if r := recover(); r != nil {
-						fmt.Printf("❌ Panic in G hotkey translation: %v\n", r)
-					}
-				}()
-				performGHotkeyTranslation()
-			}()
+	for name := range actionRequests {
+		logf("🎯 %s request received\n", name)
+		if name == actionNextProfile {
+			activateNextProfile()
+			continue
 		}
+		// Use a goroutine with proper error handling
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logf("❌ Panic in %s: %v\n", name, r)
+				}
+			}()
+			runAction(name)
+		}()
 	}
 }
 
-// startHotkeyListener bắt các sự kiện hotkey Control+Option+H/J
-func startHotkeyListener() {
-	fmt.Println("Hotkey listener started.")
-	fmt.Println("Nhấn Control+Option+H để dịch sang tiếng Anh.")
-	fmt.Println("Nhấn Control+Option+J để dịch sang cả tiếng Anh và Nhật.")
-	fmt.Println("Nhấn Control+Option+G để dịch nội dung clipboard sang ngôn ngữ đã chọn (copy vào clipboard & hiển thị alert).")
-	fmt.Printf("Sử dụng model: %s\n", getGeminiModel())
-	fmt.Printf("Ngôn ngữ cho hotkey G: %s\n", appConfig.GLanguage)
-	fmt.Println("Đang lắng nghe sự kiện hotkey...")
-
-	// Bắt đầu hook
-	evChan := hook.Start()
-	if evChan == nil {
-		fmt.Println("Lỗi: Không thể khởi động hook (nil channel)")
-		return
-	}
-	defer hook.End()
-
-	// Thời gian debouncing
-	var lastEvent time.Time
-
-	for ev := range evChan {
-		// Log sự kiện để debug (có thể xóa sau khi xác nhận hoạt động)
-		// fmt.Printf("Sự kiện: Kind=%v, Keycode=%d (0x%x), Mask=%d (0x%x), Keychar=%q\n",
-		// 	ev.Kind, ev.Keycode, ev.Keycode, ev.Mask, ev.Mask, ev.Keychar)
-
-		// Chỉ xử lý sự kiện KeyDown
-		if ev.Kind == hook.KeyDown {
-			// Mask cho Control + Option (từ log)
-			const requiredModifiers = 0xa00a // 40970
-
-			// Kiểm tra nếu đúng tổ hợp Control + Option
-			if ev.Mask == requiredModifiers {
-				// Debouncing
-				if time.Since(lastEvent) < 200*time.Millisecond {
-					continue
-				}
-				lastEvent = time.Now()
-
-				switch ev.Keycode {
-				case 0x23: // Keycode cho 'H' (từ log)
-					fmt.Printf("🎯 Phát hiện hotkey: Control+Option+H (chỉ tiếng Anh)\n")
-					fmt.Printf("   Keycode: %d (0x%x), Mask: %d (0x%x)\n", ev.Keycode, ev.Keycode, ev.Mask, ev.Mask)
-					select {
-					case translationChan <- true:
-						fmt.Println("Yêu cầu dịch tiếng Anh đã gửi")
-					default:
-						fmt.Println("⚠️ Yêu cầu dịch tiếng Anh bị bỏ qua (channel đầy)")
-					}
-
-				case 0x24: // Keycode cho 'J' (từ log)
-					fmt.Printf("🎯 Phát hiện hotkey: Control+Option+J (tiếng Anh + Nhật)\n")
-					fmt.Printf("   Keycode: %d (0x%x), Mask: %d (0x%x)\n", ev.Keycode, ev.Keycode, ev.Mask, ev.Mask)
-					fmt.Printf("   Keycode: %d (0x%x), Mask: %d (0x%x)\n", ev.Keycode, ev.Keycode, ev.Mask, ev.Mask)
-					select {
-					case dualTranslationChan <- true:
-						fmt.Println("Yêu cầu dịch tiếng Anh + Nhật đã gửi")
-					default:
-						fmt.Println("⚠️ Yêu cầu dịch tiếng Anh + Nhật bị bỏ qua (channel đầy)")
-					}
-
-				case 0x22: // Keycode cho 'G' (từ log)
-					fmt.Printf("🎯 Phát hiện hotkey: Control+Option+G (dịch sang ngôn ngữ đã chọn)\n")
-					fmt.Printf("   Keycode: %d (0x%x), Mask: %d (0x%x)\n", ev.Keycode, ev.Keycode, ev.Mask, ev.Mask)
-					select {
-					case gHotkeyTranslationChan <- true:
-						fmt.Println("Yêu cầu dịch G hotkey đã gửi")
-					default:
-						fmt.Println("⚠️ Yêu cầu dịch G hotkey bị bỏ qua (channel đầy)")
-					}
-				}
-			}
+// Apply hotkeys changed in the window or in the config file to the running listener
+func subscribeHotkeyChanges() {
+	subscribeConfig(func(config Config) {
+		if !hotkeyListenerRunning.Load() {
+			return
 		}
-	}
+		if err := setActiveHotkeys(config.Hotkeys); err != nil {
+			logf("❌ Invalid hotkeys in config: %v\n", err)
+		}
+	})
 }
 
-// Safe version using system commands instead of robotgo
-func performTranslation() {
-	fmt.Println("�� Copying selected text...")
-
-	// Add a small delay to ensure hotkey processing is complete
-	time.Sleep(150 * time.Millisecond)
-
-	// Copy selected text using system command
-	var copyCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		// On macOS, use osascript to simulate Cmd+C
-		copyCmd = exec.Command("osascript", "-e", "tell application \"System Events\" to keystroke \"c\" using command down")
-	} else {
-		// On other systems, you might need different commands
-		fmt.Println("❌ Unsupported operating system for copy command")
-		return
-	}
-
-	err := copyCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error copying text: %v\n", err)
-		return
-	}
-
-	time.Sleep(300 * time.Millisecond) // Wait for copy to complete
-
-	// Read from clipboard using system command
-	var clipboardCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		clipboardCmd = exec.Command("pbpaste")
-	} else {
-		fmt.Println("❌ Unsupported operating system for clipboard access")
-		return
-	}
-
-	clipboardOutput, err := clipboardCmd.Output()
-	if err != nil {
-		fmt.Printf("❌ Error reading clipboard: %v\n", err)
-		return
-	}
-
-	text := string(clipboardOutput)
-	if text == "" {
-		fmt.Println("⚠️  No text in clipboard")
-		return
-	}
-
-	fmt.Printf("📝 Copied text: \"%s\"\n", text)
-	fmt.Printf("📏 Text length: %d characters\n", len(text))
-
-	// Translate using Gemini API
-	fmt.Println("🌐 Translating with Gemini API...")
-	playLoadingSound()
-	translatedText, err := translateWithGemini(text, "English")
-	if err != nil {
-		fmt.Printf("❌ Translation error: %v\n", err)
-		return
-	}
-
-	fmt.Printf("✅ Translated text: \"%s\"\n", translatedText)
-
-	// Write back to clipboard and paste
-	fmt.Println("📋 Writing translated text to clipboard...")
-	var writeCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		writeCmd = exec.Command("pbcopy")
-	} else {
-		fmt.Println("❌ Unsupported operating system for clipboard write")
-		return
-	}
-
-	writeCmd.Stdin = bytes.NewReader([]byte(translatedText))
-	err = writeCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error writing to clipboard: %v\n", err)
-		return
-	}
-
-	// Paste the translated text
-	fmt.Println("📝 Pasting translated text...")
-	var pasteCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		pasteCmd = exec.Command("osascript", "-e", "tell application \"System Events\" to keystroke \"v\" using command down")
-	} else {
-		fmt.Println("❌ Unsupported operating system for paste command")
-		return
-	}
-
-	err = pasteCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error pasting text: %v\n", err)
-		return
-	}
+// The gohook hook, End panics when called twice so it is guarded
+var (
+	hookMu      sync.Mutex
+	hookStarted bool
+)
 
-	fmt.Println("✨ Translation completed!")
+// Start the global keyboard hook
+func startHook() chan hook.Event {
+	hookMu.Lock()
+	defer hookMu.Unlock()
+	evChan := hook.Start()
+	hookStarted = evChan != nil
+	return evChan
 }
 
-// Dual translation function for English + Japanese with Select All
-func performDualTranslation() {
-	fmt.Println("📋 Selecting all text and copying...")
-
-	// Add a small delay to ensure hotkey processing is complete
-	time.Sleep(150 * time.Millisecond)
-
-	// First, select all text using Cmd+A
-	var selectAllCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		// On macOS, use osascript to simulate Cmd+A
-		selectAllCmd = exec.Command("osascript", "-e", "tell application \"System Events\" to keystroke \"a\" using command down")
-	} else {
-		// On other systems, you might need different commands
-		fmt.Println("❌ Unsupported operating system for select all command")
-		return
-	}
-
-	err := selectAllCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error selecting all text: %v\n", err)
-		return
-	}
-
-	time.Sleep(200 * time.Millisecond) // Wait for select all to complete
-
-	// Then copy selected text using system command
-	var copyCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		// On macOS, use osascript to simulate Cmd+C
-		copyCmd = exec.Command("osascript", "-e", "tell application \"System Events\" to keystroke \"c\" using command down")
-	} else {
-		// On other systems, you might need different commands
-		fmt.Println("❌ Unsupported operating system for copy command")
-		return
+// Stop the global keyboard hook, this ends the event loop of startHotkeyListener
+func stopHook() {
+	hookMu.Lock()
+	defer hookMu.Unlock()
+	if hookStarted {
+		hookStarted = false
+		hook.End()
 	}
+}
 
-	err = copyCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error copying text: %v\n", err)
+// startHotkeyListener bắt các sự kiện hotkey đã cấu hình trong config
+func startHotkeyListener() {
+	config := currentConfig()
+	if errs := config.Validate(); errs != nil {
+		logf("❌ Config không hợp lệ:\n%v\n", errs)
 		return
 	}
-
-	time.Sleep(300 * time.Millisecond) // Wait for copy to complete
-
-	// Read from clipboard using system command
-	var clipboardCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		clipboardCmd = exec.Command("pbpaste")
-	} else {
-		fmt.Println("❌ Unsupported operating system for clipboard access")
+	if err := setActiveHotkeys(config.Hotkeys); err != nil {
+		logf("❌ Hotkey không hợp lệ: %v\n", err)
 		return
 	}
 
-	clipboardOutput, err := clipboardCmd.Output()
-	if err != nil {
-		fmt.Printf("❌ Error reading clipboard: %v\n", err)
-		return
-	}
+	logln("Hotkey listener started.")
+	logf("Nhấn %s để dịch sang tiếng Anh.\n", hotkeyLabel(config, actionTranslate))
+	logf("Nhấn %s để dịch sang các ngôn ngữ đã chọn.\n", hotkeyLabel(config, actionDualTranslate))
+	logf("Nhấn %s để dịch nội dung clipboard sang ngôn ngữ đã chọn (copy vào clipboard & hiển thị alert).\n", hotkeyLabel(config, actionClipboardTranslate))
+	logf("Sử dụng provider: %s, model: %s\n", config.Provider, config.Model)
+	logf("Ngôn ngữ cho hotkey G: %s\n", config.ClipboardLanguage)
+	logln("Đang lắng nghe sự kiện hotkey...")
 
-	text := string(clipboardOutput)
-	if text == "" {
-		fmt.Println("⚠️  No text in clipboard")
+	// Bắt đầu hook
+	evChan := startHook()
+	if evChan == nil {
+		logln("Lỗi: Không thể khởi động hook (nil channel)")
 		return
 	}
+	defer stopHook()
 
-	fmt.Printf("📝 Copied text: \"%s\"\n", text)
-	fmt.Printf("📏 Text length: %d characters\n", len(text))
+	hotkeyListenerRunning.Store(true)
+	defer hotkeyListenerRunning.Store(false)
 
-	// lay danh sách languages từ appConfig
-	selectedLanguages = appConfig.SelectedLanguages
-
-	// Map language codes to full names
-	languageMap := map[string]string{
-		"EN": "English",
-		"VN": "Vietnamese",
-		"JP": "Japanese",
-	}
-
-	var translations []string
-	var combinedText string
-
-	// Translate to each selected language
-	for _, langCode := range selectedLanguages {
-		if fullName, exists := languageMap[langCode]; exists {
-			fmt.Printf("🌐 Translating to %s...\n", fullName)
-			playLoadingSound()
-			translatedText, err := translateWithGemini(text, fullName)
-			if err != nil {
-				fmt.Printf("❌ %s translation error: %v\n", fullName, err)
-				continue // Skip this language if translation fails
-			}
+	// Thời gian debouncing
+	var lastEvent time.Time
 
-			// Format with or without prefix based on setting
-			var formattedText string
-			if appConfig.IncludePrefix {
-				formattedText = fmt.Sprintf("[%s]: %s", langCode, translatedText)
-			} else {
-				formattedText = translatedText
-			}
+	for ev := range evChan {
+		// Log sự kiện để debug (có thể xóa sau khi xác nhận hoạt động)
+		// logf("Sự kiện: Kind=%v, Keycode=%d (0x%x), Mask=%d (0x%x), Keychar=%q\n",
+		// 	ev.Kind, ev.Keycode, ev.Keycode, ev.Mask, ev.Mask, ev.Keychar)
 
-			translations = append(translations, formattedText)
-			fmt.Printf("✅ %s: \"%s\"\n", langCode, translatedText)
+		// Chỉ xử lý sự kiện KeyDown
+		if ev.Kind != hook.KeyDown {
+			continue
 		}
-	}
 
-	// Combine all translations
-	if len(translations) > 0 {
-		combinedText = strings.Join(translations, "\n----------------\n")
-	} else {
-		fmt.Println("⚠️ No valid languages selected for translation")
-		return
-	}
-
-	// Write back to clipboard and paste
-	fmt.Println("📋 Writing combined translations to clipboard...")
-	var writeCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		writeCmd = exec.Command("pbcopy")
-	} else {
-		fmt.Println("❌ Unsupported operating system for clipboard write")
-		return
-	}
+		// Đang ghi hotkey mới từ phần cài đặt
+		if recordHotkeyEvent(ev) {
+			continue
+		}
 
-	writeCmd.Stdin = bytes.NewReader([]byte(combinedText))
-	err = writeCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error writing to clipboard: %v\n", err)
-		return
-	}
+		binding, ok := matchHotkey(ev)
+		if !ok {
+			continue
+		}
 
-	// Paste the translated text
-	fmt.Println("📝 Pasting combined translations...")
-	var pasteCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		pasteCmd = exec.Command("osascript", "-e", "tell application \"System Events\" to keystroke \"v\" using command down")
-	} else {
-		fmt.Println("❌ Unsupported operating system for paste command")
-		return
-	}
+		// Debouncing
+		if time.Since(lastEvent) < 200*time.Millisecond {
+			continue
+		}
+		lastEvent = time.Now()
 
-	err = pasteCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error pasting text: %v\n", err)
-		return
+		logf("🎯 Phát hiện hotkey: %s (%s)\n", binding.hotkey.Label(), binding.action)
+		logf("   Keycode: %d (0x%x), Mask: %d (0x%x)\n", ev.Keycode, ev.Keycode, ev.Mask, ev.Mask)
+		triggerAction(binding.action)
 	}
-
-	fmt.Println("✨ Dual translation completed!")
 }
 
-// G hotkey translation function that shows alert
-func performGHotkeyTranslation() {
-
-	fmt.Println("📋 Copying selected text and reading clipboard content...")
-
-	// Add a small delay to ensure hotkey processing is complete
-	time.Sleep(150 * time.Millisecond)
-
-	// First, copy selected text using Cmd+C
-	var copyCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		// On macOS, use osascript to simulate Cmd+C
-		copyCmd = exec.Command("osascript", "-e", "tell application \"System Events\" to keystroke \"c\" using command down")
-	} else {
-		// On other systems, you might need different commands
-		fmt.Println("❌ Unsupported operating system for copy command")
-		return
-	}
-
-	err := copyCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error copying text: %v\n", err)
-		return
-	}
-
-	time.Sleep(300 * time.Millisecond) // Wait for copy to complete
-
-	// Read from clipboard using system command
-	var clipboardCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		clipboardCmd = exec.Command("pbpaste")
-	} else {
-		fmt.Println("❌ Unsupported operating system for clipboard access")
-		return
-	}
-
-	clipboardOutput, err := clipboardCmd.Output()
-	if err != nil {
-		fmt.Printf("❌ Error reading clipboard: %v\n", err)
-		return
-	}
-
-	text := string(clipboardOutput)
-	if text == "" {
-		fmt.Println("⚠️  No text in clipboard")
-		// Show alert for empty clipboard
-		showAlert("Notification", "No content in clipboard!")
-		return
-	}
-
-	fmt.Printf("📝 Clipboard text: \"%s\"\n", text)
-	fmt.Printf("📏 Text length: %d characters\n", len(text))
-
-	// Get selected language from config
-	config := loadConfig()
-	selectedLangCode := config.GLanguage
-
-	// Map language codes to full names
-	languageMap := map[string]string{
-		"EN": "English",
-		"VN": "Vietnamese",
-		"JP": "Japanese",
-	}
-
-	fullLanguageName, exists := languageMap[selectedLangCode]
-	if !exists {
-		fullLanguageName = "Vietnamese" // Fallback
-		selectedLangCode = "VN"
-	}
-
-	// Translate using Gemini API
-	fmt.Printf("🌐 Translating to %s with Gemini API...\n", fullLanguageName)
-	playLoadingSound()
-	translatedText, err := translateWithGemini(text, fullLanguageName)
-	if err != nil {
-		fmt.Printf("❌ Translation error: %v\n", err)
-		showAlert("Error", fmt.Sprintf("Translate error: %v", err))
-		return
-	}
-
-	fmt.Printf("✅ Translated text: \"%s\"\n", translatedText)
-
-	// Copy translated text to clipboard
-	fmt.Println("📋 Copying translated text to clipboard...")
-	var writeCmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		writeCmd = exec.Command("pbcopy")
-	} else {
-		fmt.Println("❌ Unsupported operating system for clipboard write")
-		showAlert(fmt.Sprintf("Translation (%s)", selectedLangCode), translatedText)
-		return
-	}
-
-	writeCmd.Stdin = bytes.NewReader([]byte(translatedText))
-	err = writeCmd.Run()
-	if err != nil {
-		fmt.Printf("❌ Error writing to clipboard: %v\n", err)
-		showAlert("Error", fmt.Sprintf("Error copying to clipboard: %v", err))
-		return
+// Send an action to handleTranslationRequests, false when too many are already waiting
+func triggerAction(action string) bool {
+	select {
+	case actionRequests <- action:
+		logf("Yêu cầu %s đã gửi\n", action)
+		return true
+	default:
+		logf("⚠️ Yêu cầu %s bị bỏ qua (channel đầy)\n", action)
+		return false
 	}
-
-	fmt.Println("✅ Translated text copied to clipboard successfully")
-
-	// Show alert with translated text
-	showAlert(fmt.Sprintf("Translation (%s)", selectedLangCode), translatedText)
 }
 
 // Function to play loading sound
@@ -1119,91 +1422,58 @@ func playLoadingSound() {
 
 // Function to show alert using osascript
 func showAlert(title, message string) {
-	if runtime.GOOS != "darwin" {
-		fmt.Printf("⚠️ Alert not supported on this OS: %s - %s\n", title, message)
+	// Dialogs need a desktop session on macOS, notifications work everywhere
+	if runtime.GOOS != "darwin" || headlessMode.Load() {
+		showNotification(title, message)
 		return
 	}
+	title, message = redact(title), redact(message)
 
-	// Escape quotes in message for osascript
-	escapedMessage := strings.ReplaceAll(message, "\"", "\\\"")
-	escapedTitle := strings.ReplaceAll(title, "\"", "\\\"")
-
-	// Create osascript command using display dialog
-	cmd := exec.Command("osascript", "-e", fmt.Sprintf("tell application \"System Events\" to display dialog \"%s\" buttons {\"OK\"} default button \"OK\" with title \"%s\"", escapedMessage, escapedTitle))
+	// Pass the text as arguments of the script, translations and errors can contain quotes and backslashes
+	cmd := exec.Command("osascript",
+		"-e", "on run argv",
+		"-e", "tell application \"System Events\" to display dialog (item 1 of argv) buttons {\"OK\"} default button \"OK\" with title (item 2 of argv)",
+		"-e", "end run",
+		message, title)
 
 	err := cmd.Run()
 	if err != nil {
-		fmt.Printf("❌ Error showing alert: %v\n", err)
+		logf("❌ Error showing alert: %v\n", err)
 	} else {
-		fmt.Println("✅ Alert displayed successfully")
+		logln("✅ Alert displayed successfully")
 	}
 }
 
-func translateWithGemini(text string, language string) (string, error) {
-	// Get API key and model from config
-	apiKey := getGeminiAPIKey()
-	model := getGeminiModel()
-	geminiAPIURL := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", model, apiKey)
-
-	// Prepare prompt for translation with improvement
-	prompt := fmt.Sprintf("Please translate the following text to %s and improve/rephrase it to make it more clear, natural, and easy to understand. Return only the improved translated result without any additional explanation: \"%s\"", language, text)
-
-	reqBody := GeminiRequest{
-		Contents: []struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		}{
-			{
-				Parts: []struct {
-					Text string `json:"text"`
-				}{
-					{Text: prompt},
-				},
-			},
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
-	}
-
-	req, err := http.NewRequest("POST", geminiAPIURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	var geminiResp GeminiResponse
-	err = json.Unmarshal(body, &geminiResp)
-	if err != nil {
-		return "", err
-	}
-
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no translation received")
+// Show a result in a window to review it, Copy puts the (edited) text on the clipboard.
+// Without a window a notification is shown instead.
+func showPreview(title, text string) {
+	if headlessMode.Load() {
+		showNotification(title, text)
+		return
 	}
 
-	// Clean up the response text
-	result := strings.TrimSpace(geminiResp.Candidates[0].Content.Parts[0].Text)
-
-	// Remove any potential quotes around the result
-	if strings.HasPrefix(result, "\"") && strings.HasSuffix(result, "\"") {
-		result = result[1 : len(result)-1]
-	}
+	fyne.Do(func() {
+		previewWindow := fyne.CurrentApp().NewWindow(title)
+		entry := widget.NewMultiLineEntry()
+		entry.SetText(text)
+		entry.Wrapping = fyne.TextWrapWord
 
-	return result, nil
+		copyButton := widget.NewButton("Copy", func() {
+			if err := systemClipboard.Write(entry.Text); err != nil {
+				logf("❌ Error writing to clipboard: %v\n", err)
+				dialog.ShowError(err, previewWindow)
+				return
+			}
+			logln("📋 Preview copied to clipboard")
+			previewWindow.Close()
+		})
+		copyButton.Importance = widget.HighImportance
+		closeButton := widget.NewButton("Close", previewWindow.Close)
+
+		previewWindow.SetContent(container.NewBorder(nil, container.NewHBox(closeButton, copyButton), nil, nil, entry))
+		previewWindow.Resize(fyne.NewSize(420, 260))
+		previewWindow.CenterOnScreen()
+		previewWindow.Show()
+		previewWindow.RequestFocus()
+	})
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Point the config path at a temporary directory so tests never read or write the
+// user's config, prompt templates or credentials, and keep the log quiet
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "superkeyboard-test-")
+	if err != nil {
+		panic(err)
+	}
+	configFlag = filepath.Join(dir, "config.json")
+	logOutput = io.Discard
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// Get a built-in language by code
+func testLanguage(t *testing.T, code string) Language {
+	t.Helper()
+	language, ok := findLanguage(Config{}, code)
+	if !ok {
+		t.Fatalf("unknown language %q", code)
+	}
+	return language
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// How long a fetched model list is used before it is refreshed at startup
+const geminiModelsCacheTTL = 7 * 24 * time.Hour
+
+// Response struct for the Gemini models.list API
+type geminiModelsResponse struct {
+	Models []struct {
+		Name                       string   `json:"name"`
+		SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	} `json:"models"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// Get Gemini models for the dropdown, cached list first then the built-in one
+func availableGeminiModels(config Config) []string {
+	if len(config.GeminiModels) > 0 {
+		return config.GeminiModels
+	}
+	return geminiModels
+}
+
+// Check if the cached model list should be refreshed
+func geminiModelsCacheExpired(config Config) bool {
+	return len(config.GeminiModels) == 0 || time.Since(config.GeminiModelsFetchedAt) > geminiModelsCacheTTL
+}
+
+// Fetch the models that support generateContent from the Gemini models.list API
+func fetchGeminiModels(apiKey string, baseURL string) ([]string, error) {
+	var models []string
+	pageToken := ""
+
+	for {
+		query := url.Values{}
+		query.Set("pageSize", "1000")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		var resp geminiModelsResponse
+		headers := map[string]string{"x-goog-api-key": apiKey}
+		if err := getJSON(baseURL+"/models?"+query.Encode(), headers, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, model := range resp.Models {
+			if contains(model.SupportedGenerationMethods, "generateContent") {
+				models = append(models, strings.TrimPrefix(model.Name, "models/"))
+			}
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models supporting generateContent found")
+	}
+	return models, nil
+}
+
+// Fetch the live model list and cache it in config
+func refreshGeminiModels() ([]string, error) {
+	config := currentConfig()
+	if config.GeminiAPIKey == "" {
+		return nil, fmt.Errorf("a Gemini API key is required to fetch models")
+	}
+
+	logln("🔄 Fetching Gemini models...")
+	models, err := fetchGeminiModels(config.GeminiAPIKey, geminiBaseURL(config))
+	if err != nil {
+		logf("❌ Error fetching Gemini models, using cached list: %v\n", err)
+		return availableGeminiModels(config), err
+	}
+
+	err = updateConfig(func(config *Config) {
+		config.GeminiModels = models
+		config.GeminiModelsFetchedAt = time.Now()
+	})
+	if err != nil {
+		logf("❌ Error saving model list: %v\n", err)
+	}
+
+	logf("✅ Fetched %d Gemini models\n", len(models))
+	return models, nil
+}
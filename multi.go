@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Default number of languages translated at the same time when each needs its own request
+const defaultTranslationWorkers = 3
+
+// MultiTranslator is implemented by backends that can translate into several languages with one request
+type MultiTranslator interface {
+	TranslateMulti(text string, targets []Language) (map[string]string, error)
+}
+
+// Result of translating into one target language
+type languageTranslation struct {
+	Language Language
+	Text     string
+	Err      error
+}
+
+// Returned when a multi-language reply can't be used, the languages are then translated one by one
+var errUnusableMultiReply = errors.New("unusable multi-language reply")
+
+// Parse and validate the JSON object returned for a multi-language translation
+func parseMultiTranslation(result string, targets []Language) (map[string]string, error) {
+	// Some models still wrap the JSON in a markdown code block
+	result = strings.TrimSpace(result)
+	result = strings.TrimPrefix(result, "```json")
+	result = strings.TrimPrefix(result, "```")
+	result = strings.TrimSuffix(result, "```")
+
+	var translations map[string]string
+	if err := json.Unmarshal([]byte(result), &translations); err != nil {
+		return nil, fmt.Errorf("%w: malformed JSON response: %v", errUnusableMultiReply, err)
+	}
+
+	for _, target := range targets {
+		text := cleanTranslation(translations[target.Code])
+		if text == "" {
+			return nil, fmt.Errorf("%w: missing translation for %s in JSON response", errUnusableMultiReply, target.Code)
+		}
+		translations[target.Code] = text
+	}
+	return translations, nil
+}
+
+// Translate text into all targets, in the order of targets. Uses a single request when the
+// backend supports it and falls back to one request per language when its reply can't be used.
+func translateToLanguages(config Config, text string, targets []Language) []languageTranslation {
+	translator, err := newTranslator(config)
+	if err != nil {
+		return failedTranslations(targets, err)
+	}
+
+	if multi, ok := translator.(MultiTranslator); ok && len(targets) > 1 {
+		logf("🌐 Translating to %d languages in one request...\n", len(targets))
+		translations, err := multi.TranslateMulti(text, targets)
+		if err == nil {
+			results := make([]languageTranslation, len(targets))
+			for i, target := range targets {
+				results[i] = languageTranslation{Language: target, Text: translations[target.Code]}
+			}
+			return results
+		}
+		// A rejected or failed request would fail again for every language
+		if !errors.Is(err, errUnusableMultiReply) {
+			return failedTranslations(targets, err)
+		}
+		logf("⚠️ Single request translation failed, translating each language separately: %v\n", err)
+	}
+
+	return translateConcurrently(translator, text, targets, translationWorkers(config))
+}
+
+// Give every target the same error
+func failedTranslations(targets []Language, err error) []languageTranslation {
+	results := make([]languageTranslation, len(targets))
+	for i, target := range targets {
+		results[i] = languageTranslation{Language: target, Err: err}
+	}
+	return results
+}
+
+// Get the worker limit for per-language translation from config
+func translationWorkers(config Config) int {
+	if config.TranslationWorkers <= 0 {
+		return defaultTranslationWorkers
+	}
+	return config.TranslationWorkers
+}
+
+// Translate text into each target with its own request, running at most workers requests at once.
+// Results keep the order of targets.
+func translateConcurrently(translator Translator, text string, targets []Language, workers int) []languageTranslation {
+	results := make([]languageTranslation, len(targets))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			logf("🌐 Translating to %s...\n", target.Name)
+			translatedText, err := translator.Translate(text, target)
+			results[i] = languageTranslation{Language: target, Text: translatedText, Err: err}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Short reason shown inline for a failed language, e.g. "429" for a rate limited request
+func translationFailureReason(err error) string {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return fmt.Sprintf("%d", statusErr.StatusCode)
+	}
+	return redact(err.Error())
+}
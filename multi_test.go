@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Translate to English and Japanese with Gemini pointed at handler, returns the results and the request count
+func translateWithGeminiServer(t *testing.T, handler func(w http.ResponseWriter, body string)) ([]languageTranslation, int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		data, _ := io.ReadAll(r.Body)
+		handler(w, string(data))
+	}))
+	t.Cleanup(server.Close)
+
+	config := Config{Provider: providerGemini, Model: "test-model", GeminiAPIKey: "gemini-key", GeminiBaseURL: server.URL}
+	targets := []Language{testLanguage(t, "en"), testLanguage(t, "ja")}
+	return translateToLanguages(config, "Xin chào", targets), requests.Load()
+}
+
+func TestTranslateToLanguagesSingleRequest(t *testing.T) {
+	results, requests := translateWithGeminiServer(t, func(w http.ResponseWriter, body string) {
+		io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"{\"en\":\"Hello\",\"ja\":\"こんにちは\"}"}]}}]}`)
+	})
+	if requests != 1 {
+		t.Errorf("%d requests, want 1", requests)
+	}
+	if results[0].Text != "Hello" || results[1].Text != "こんにちは" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestTranslateToLanguagesFallsBackOnUnusableReply(t *testing.T) {
+	results, requests := translateWithGeminiServer(t, func(w http.ResponseWriter, body string) {
+		switch {
+		case strings.Contains(body, "responseSchema"):
+			// The structured reply is missing a language
+			io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"{\"en\":\"Hello\"}"}]}}]}`)
+		case strings.Contains(body, "Japanese"):
+			io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"こんにちは"}]}}]}`)
+		default:
+			io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"Hello"}]}}]}`)
+		}
+	})
+	if requests != 3 {
+		t.Errorf("%d requests, want 1 structured and 2 per-language requests", requests)
+	}
+	if results[0].Text != "Hello" || results[1].Text != "こんにちは" {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestTranslateToLanguagesKeepsRequestErrors(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			results, requests := translateWithGeminiServer(t, func(w http.ResponseWriter, body string) {
+				w.WriteHeader(status)
+			})
+			if requests != 1 {
+				t.Errorf("%d requests, want no per-language retries", requests)
+			}
+			for _, result := range results {
+				var statusErr *httpStatusError
+				if !errors.As(result.Err, &statusErr) || statusErr.StatusCode != status {
+					t.Errorf("%s error = %v, want HTTP %d", result.Language.Code, result.Err, status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Set when running without a window, alerts become notifications
+var headlessMode atomic.Bool
+
+// How long notifications stay on screen
+const notificationTimeoutMs = 5000
+
+// Show a desktop notification that goes away by itself, secrets in backend errors are removed
+func showNotification(title, message string) {
+	title, message = redact(title), redact(message)
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		// Pass the text as arguments of the script, so nothing in it needs escaping
+		_, err = runCommand("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 1 of argv) with title (item 2 of argv)",
+			"-e", "end run",
+			message, title)
+	case "linux":
+		// The notification bus works without extra tools, notify-send is the fallback
+		if err = notifyDBus(title, message); err != nil && hasCommand("notify-send") {
+			_, err = runCommand("notify-send", "--app-name=superkeyboard", title, message)
+		}
+	default:
+		err = fmt.Errorf("not supported on %s", runtime.GOOS)
+	}
+
+	if err != nil {
+		logf("⚠️ Cannot show notification (%s - %s): %v\n", title, message, err)
+	}
+}
+
+// Send a notification through org.freedesktop.Notifications on the session bus
+func notifyDBus(title, message string) error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	obj := conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	return obj.Call("org.freedesktop.Notifications.Notify", 0,
+		"superkeyboard", uint32(0), "", title, message,
+		[]string{}, map[string]dbus.Variant{}, int32(notificationTimeoutMs)).Err
+}
+
+// Functions called with every failed action, e.g. to emit D-Bus signals
+var (
+	errorSubscribersMu sync.Mutex
+	errorSubscribers   []func(title string, err error)
+)
+
+// Call fn with every failed action
+func subscribeErrors(fn func(title string, err error)) {
+	errorSubscribersMu.Lock()
+	defer errorSubscribersMu.Unlock()
+	errorSubscribers = append(errorSubscribers, fn)
+}
+
+// Tell the user about a failed action, only the log is used while the window is open
+func notifyError(title string, err error) {
+	if headlessMode.Load() {
+		showNotification(title, err.Error())
+	}
+	broadcastError(title, err)
+}
+
+// Pass an error to the subscribers only, for errors the user has already been shown
+func broadcastError(title string, err error) {
+	errorSubscribersMu.Lock()
+	subscribers := slices.Clone(errorSubscribers)
+	errorSubscribersMu.Unlock()
+	for _, fn := range subscribers {
+		fn(title, err)
+	}
+}
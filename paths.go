@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Name of the per-user config directory
+const appDirName = "superkeyboard"
+
+// Environment variable that overrides the config file path
+const configPathEnv = "SUPERKEYBOARD_CONFIG"
+
+// Config path from the --config flag, empty to use the default location
+var configFlag string
+
+var (
+	configPathOnce sync.Once
+	configPath     string
+)
+
+// Register the command line flags and parse them
+func parseFlags() {
+	flag.StringVar(&configFlag, "config", "", "path of the config file (default: per-user config directory)")
+	flag.Parse()
+}
+
+// Get the path of config.json, resolved once: --config flag, then $SUPERKEYBOARD_CONFIG,
+// then the per-user config directory
+func getConfigPath() string {
+	configPathOnce.Do(func() {
+		configPath = resolveConfigPath()
+		logf("📁 Config path: %s\n", configPath)
+	})
+	return configPath
+}
+
+func resolveConfigPath() string {
+	if configFlag != "" {
+		return configFlag
+	}
+	if path := os.Getenv(configPathEnv); path != "" {
+		return path
+	}
+
+	dir, err := userConfigDir()
+	if err != nil {
+		logf("⚠️ Cannot get user config directory, using current directory: %v\n", err)
+		return "config.json"
+	}
+	path := filepath.Join(dir, "config.json")
+	migrateLegacyConfig(dir)
+	return path
+}
+
+// Get the per-user config directory, $XDG_CONFIG_HOME/superkeyboard on Linux and
+// ~/Library/Application Support/superkeyboard on macOS
+func userConfigDir() (string, error) {
+	if runtime.GOOS == "linux" {
+		if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+			return filepath.Join(dir, appDirName), nil
+		}
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appDirName), nil
+}
+
+// Move config.json from the executable directory of older versions to the config directory.
+// Only runs when the config directory has no config.json yet.
+func migrateLegacyConfig(dir string) {
+	path := filepath.Join(dir, "config.json")
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	execPath, err := os.Executable()
+	if err != nil {
+		return
+	}
+	oldPath := filepath.Join(filepath.Dir(execPath), "config.json")
+	data, err := os.ReadFile(oldPath)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logf("❌ Cannot create config directory %s: %v\n", dir, err)
+		return
+	}
+	logf("📦 Moving config from %s to %s\n", oldPath, path)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		logf("❌ Cannot copy config: %v\n", err)
+		return
+	}
+	// The old location may be read-only, the copy is used from now on either way
+	if err := os.Remove(oldPath); err != nil {
+		logf("⚠️ Cannot remove old %s: %v\n", oldPath, err)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Clipboard reads and writes the system clipboard as text, Save and Restore
+// also keep non-text content such as images where the backend supports it
+type Clipboard interface {
+	Name() string
+	Read() (string, error)
+	Write(text string) error
+	Save() (ClipboardContent, error)
+	Restore(content ClipboardContent) error
+}
+
+// KeySender simulates the copy, paste and select-all shortcuts in the focused application
+type KeySender interface {
+	Name() string
+	Copy() error
+	Paste() error
+	SelectAll() error
+}
+
+// Clipboard and key sender of the current desktop, set by initPlatform
+var (
+	systemClipboard Clipboard
+	systemKeys      KeySender
+)
+
+// Pick the clipboard and key sender for the running OS and display server
+func initPlatform() error {
+	var err error
+	systemClipboard, err = detectClipboard()
+	if err != nil {
+		return err
+	}
+	systemKeys, err = detectKeySender()
+	if err != nil {
+		return err
+	}
+	logf("🖥️ Using clipboard: %s, keys: %s\n", systemClipboard.Name(), systemKeys.Name())
+	return nil
+}
+
+// Check if the session runs on Wayland
+func isWayland() bool {
+	return os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("XDG_SESSION_TYPE") == "wayland"
+}
+
+// Check if an X11 display is available, including XWayland
+func isX11() bool {
+	return os.Getenv("DISPLAY") != ""
+}
+
+// Check if a command is on PATH
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// Find a clipboard implementation for the current desktop
+func detectClipboard() (Clipboard, error) {
+	if runtime.GOOS == "darwin" {
+		return macClipboard{}, nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("unsupported operating system for clipboard access: %s", runtime.GOOS)
+	}
+
+	if isWayland() && hasCommand("wl-copy") && hasCommand("wl-paste") {
+		return waylandClipboard{}, nil
+	}
+	if isX11() {
+		if hasCommand("xclip") {
+			return xclipClipboard{}, nil
+		}
+		if hasCommand("xsel") {
+			return xselClipboard{}, nil
+		}
+	}
+	return nil, fmt.Errorf("no clipboard tool found, install wl-clipboard (Wayland) or xclip/xsel (X11)")
+}
+
+// Find a key sender implementation for the current desktop
+func detectKeySender() (KeySender, error) {
+	if runtime.GOOS == "darwin" {
+		return macKeySender{}, nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("unsupported operating system for keystrokes: %s", runtime.GOOS)
+	}
+
+	if isWayland() {
+		if hasCommand("wtype") {
+			return wtypeKeySender{}, nil
+		}
+		if hasCommand("ydotool") {
+			return ydotoolKeySender{}, nil
+		}
+	}
+	if isX11() && hasCommand("xdotool") {
+		return xdotoolKeySender{}, nil
+	}
+	return nil, fmt.Errorf("no keystroke tool found, install wtype or ydotool (Wayland) or xdotool (X11)")
+}
+
+// Run a command and return its stdout
+func runCommand(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %v: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+	return string(output), nil
+}
+
+// Run a command with text on stdin. Output is not captured because xclip and
+// wl-copy fork a child that keeps serving the clipboard and holds the pipes open.
+func runWithInput(text string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// macClipboard uses pbcopy and pbpaste
+type macClipboard struct{}
+
+func (macClipboard) Name() string { return "pbcopy" }
+
+func (macClipboard) Read() (string, error) {
+	return runCommand("pbpaste")
+}
+
+func (macClipboard) Write(text string) error {
+	return runWithInput(text, "pbcopy")
+}
+
+// macKeySender sends keystrokes through System Events with osascript
+type macKeySender struct{}
+
+func (macKeySender) Name() string { return "osascript" }
+
+func (macKeySender) keystroke(key string) error {
+	_, err := runCommand("osascript", "-e", fmt.Sprintf("tell application \"System Events\" to keystroke \"%s\" using command down", key))
+	return err
+}
+
+func (m macKeySender) Copy() error      { return m.keystroke("c") }
+func (m macKeySender) Paste() error     { return m.keystroke("v") }
+func (m macKeySender) SelectAll() error { return m.keystroke("a") }
+
+// xclipClipboard uses xclip on X11
+type xclipClipboard struct{}
+
+func (xclipClipboard) Name() string { return "xclip" }
+
+func (xclipClipboard) Read() (string, error) {
+	return runCommand("xclip", "-selection", "clipboard", "-out")
+}
+
+func (xclipClipboard) Write(text string) error {
+	return runWithInput(text, "xclip", "-selection", "clipboard", "-in")
+}
+
+// xselClipboard uses xsel on X11
+type xselClipboard struct{}
+
+func (xselClipboard) Name() string { return "xsel" }
+
+func (xselClipboard) Read() (string, error) {
+	return runCommand("xsel", "--clipboard", "--output")
+}
+
+func (xselClipboard) Write(text string) error {
+	return runWithInput(text, "xsel", "--clipboard", "--input")
+}
+
+// waylandClipboard uses wl-copy and wl-paste from wl-clipboard
+type waylandClipboard struct{}
+
+func (waylandClipboard) Name() string { return "wl-clipboard" }
+
+func (waylandClipboard) Read() (string, error) {
+	return runCommand("wl-paste", "--no-newline")
+}
+
+func (waylandClipboard) Write(text string) error {
+	return runWithInput(text, "wl-copy")
+}
+
+// xdotoolKeySender sends keystrokes with xdotool on X11
+type xdotoolKeySender struct{}
+
+func (xdotoolKeySender) Name() string { return "xdotool" }
+
+func (xdotoolKeySender) key(combo string) error {
+	// Release the hotkey modifiers that are still held down
+	_, err := runCommand("xdotool", "key", "--clearmodifiers", combo)
+	return err
+}
+
+func (x xdotoolKeySender) Copy() error      { return x.key("ctrl+c") }
+func (x xdotoolKeySender) Paste() error     { return x.key("ctrl+v") }
+func (x xdotoolKeySender) SelectAll() error { return x.key("ctrl+a") }
+
+// wtypeKeySender sends keystrokes with wtype on Wayland compositors that support virtual keyboards
+type wtypeKeySender struct{}
+
+func (wtypeKeySender) Name() string { return "wtype" }
+
+func (wtypeKeySender) key(key string) error {
+	_, err := runCommand("wtype", "-M", "ctrl", key, "-m", "ctrl")
+	return err
+}
+
+func (w wtypeKeySender) Copy() error      { return w.key("c") }
+func (w wtypeKeySender) Paste() error     { return w.key("v") }
+func (w wtypeKeySender) SelectAll() error { return w.key("a") }
+
+// ydotoolKeySender sends keystrokes through the ydotoold uinput daemon
+type ydotoolKeySender struct{}
+
+func (ydotoolKeySender) Name() string { return "ydotool" }
+
+// Linux input event codes used by ydotool
+const (
+	evKeyLeftCtrl = 29
+	evKeyA        = 30
+	evKeyC        = 46
+	evKeyV        = 47
+)
+
+func (ydotoolKeySender) key(code int) error {
+	_, err := runCommand("ydotool", "key",
+		fmt.Sprintf("%d:1", evKeyLeftCtrl), fmt.Sprintf("%d:1", code),
+		fmt.Sprintf("%d:0", code), fmt.Sprintf("%d:0", evKeyLeftCtrl))
+	return err
+}
+
+func (y ydotoolKeySender) Copy() error      { return y.key(evKeyC) }
+func (y ydotoolKeySender) Paste() error     { return y.key(evKeyV) }
+func (y ydotoolKeySender) SelectAll() error { return y.key(evKeyA) }
+
+// Check if clipboard and keystrokes are available before running an action
+func platformReady() bool {
+	if systemClipboard == nil || systemKeys == nil {
+		logln("❌ Clipboard or keystrokes are not supported on this system")
+		return false
+	}
+	return true
+}
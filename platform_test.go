@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Put fake clipboard and keystroke tools on an otherwise empty PATH. Each one appends its
+// name, arguments and stdin to a log and prints its entry of outputs. Returns a function
+// that reads the calls logged so far.
+func fakeCommands(t *testing.T, outputs map[string]string) func() []string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake commands are shell scripts")
+	}
+	cat, err := exec.LookPath("cat")
+	if err != nil {
+		t.Skip("cat is needed by the fake commands")
+	}
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "calls.log")
+	for name, output := range outputs {
+		script := fmt.Sprintf("#!/bin/sh\ninput=$(%s)\necho \"%s $* | $input\" >> %q\nprintf '%%s' '%s'\n", cat, name, logPath, output)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+
+	return func() []string {
+		data, err := os.ReadFile(logPath)
+		if err != nil {
+			return nil
+		}
+		return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+}
+
+// Set the variables isWayland and isX11 look at
+func setDisplay(t *testing.T, wayland bool, x11 bool) {
+	t.Helper()
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("XDG_SESSION_TYPE", "x11")
+	t.Setenv("DISPLAY", "")
+	if wayland {
+		t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+		t.Setenv("XDG_SESSION_TYPE", "wayland")
+	}
+	if x11 {
+		t.Setenv("DISPLAY", ":0")
+	}
+}
+
+func TestDetectClipboard(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("backend selection is only tested on Linux")
+	}
+	tests := []struct {
+		name     string
+		wayland  bool
+		x11      bool
+		commands []string
+		want     string // Name of the backend, empty for an error
+	}{
+		{"wayland first", true, true, []string{"wl-copy", "wl-paste", "xclip", "xsel"}, "wl-clipboard"},
+		{"wayland without wl-paste", true, true, []string{"wl-copy", "xclip"}, "xclip"},
+		{"xclip before xsel", false, true, []string{"xclip", "xsel"}, "xclip"},
+		{"xsel without xclip", false, true, []string{"xsel"}, "xsel"},
+		{"x11 tools without display", true, false, []string{"xclip", "xsel"}, ""},
+		{"no tools", true, true, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputs := map[string]string{}
+			for _, name := range tt.commands {
+				outputs[name] = ""
+			}
+			fakeCommands(t, outputs)
+			setDisplay(t, tt.wayland, tt.x11)
+
+			clipboard, err := detectClipboard()
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("detectClipboard() = %s, want an error", clipboard.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clipboard.Name() != tt.want {
+				t.Errorf("detectClipboard() = %s, want %s", clipboard.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectKeySender(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("backend selection is only tested on Linux")
+	}
+	tests := []struct {
+		name     string
+		wayland  bool
+		x11      bool
+		commands []string
+		want     string // Name of the backend, empty for an error
+	}{
+		{"wtype first", true, true, []string{"wtype", "ydotool", "xdotool"}, "wtype"},
+		{"ydotool without wtype", true, true, []string{"ydotool", "xdotool"}, "ydotool"},
+		{"xwayland fallback", true, true, []string{"xdotool"}, "xdotool"},
+		{"x11", false, true, []string{"wtype", "xdotool"}, "xdotool"},
+		{"wayland tools on x11", false, true, []string{"wtype", "ydotool"}, ""},
+		{"no tools", true, true, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputs := map[string]string{}
+			for _, name := range tt.commands {
+				outputs[name] = ""
+			}
+			fakeCommands(t, outputs)
+			setDisplay(t, tt.wayland, tt.x11)
+
+			keys, err := detectKeySender()
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("detectKeySender() = %s, want an error", keys.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keys.Name() != tt.want {
+				t.Errorf("detectKeySender() = %s, want %s", keys.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestClipboardCommands(t *testing.T) {
+	tests := []struct {
+		clipboard Clipboard
+		read      string
+		write     string
+	}{
+		{xclipClipboard{}, "xclip -selection clipboard -out | ", "xclip -selection clipboard -in | hello"},
+		{xselClipboard{}, "xsel --clipboard --output | ", "xsel --clipboard --input | hello"},
+		{waylandClipboard{}, "wl-paste --no-newline | ", "wl-copy  | hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clipboard.Name(), func(t *testing.T) {
+			calls := fakeCommands(t, map[string]string{"xclip": "copied", "xsel": "copied", "wl-paste": "copied", "wl-copy": ""})
+
+			text, err := tt.clipboard.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != "copied" {
+				t.Errorf("Read() = %q, want %q", text, "copied")
+			}
+			if err := tt.clipboard.Write("hello"); err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{tt.read, tt.write}
+			if got := calls(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("calls = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestClipboardSaveImage(t *testing.T) {
+	tests := []struct {
+		clipboard Clipboard
+		want      []string
+	}{
+		{xclipClipboard{}, []string{
+			"xclip -selection clipboard -target TARGETS -out | ",
+			"xclip -selection clipboard -target image/png -out | ",
+			"xclip -selection clipboard -target image/png -in | image/png",
+		}},
+		{waylandClipboard{}, []string{
+			"wl-paste --list-types | ",
+			"wl-paste --type image/png | ",
+			"wl-copy --type image/png | image/png",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clipboard.Name(), func(t *testing.T) {
+			// Listing the types and reading the image print the same, the type is enough to check the commands
+			calls := fakeCommands(t, map[string]string{"xclip": "image/png", "wl-paste": "image/png", "wl-copy": ""})
+
+			content, err := tt.clipboard.Save()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content.Type != "image/png" {
+				t.Errorf("Save() type = %q, want image/png", content.Type)
+			}
+			if err := tt.clipboard.Restore(content); err != nil {
+				t.Fatal(err)
+			}
+			if got := calls(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipboardCommandMissing(t *testing.T) {
+	fakeCommands(t, map[string]string{})
+	if _, err := (xclipClipboard{}).Read(); err == nil {
+		t.Error("Read() without xclip succeeded")
+	}
+	if err := (waylandClipboard{}).Write("hello"); err == nil {
+		t.Error("Write() without wl-copy succeeded")
+	}
+	if err := (xdotoolKeySender{}).Copy(); err == nil {
+		t.Error("Copy() without xdotool succeeded")
+	}
+}
+
+func TestKeySenderCommands(t *testing.T) {
+	tests := []struct {
+		keys KeySender
+		want []string // Calls of Copy, Paste and SelectAll
+	}{
+		{xdotoolKeySender{}, []string{
+			"xdotool key --clearmodifiers ctrl+c | ",
+			"xdotool key --clearmodifiers ctrl+v | ",
+			"xdotool key --clearmodifiers ctrl+a | ",
+		}},
+		{wtypeKeySender{}, []string{
+			"wtype -M ctrl c -m ctrl | ",
+			"wtype -M ctrl v -m ctrl | ",
+			"wtype -M ctrl a -m ctrl | ",
+		}},
+		{ydotoolKeySender{}, []string{
+			"ydotool key 29:1 46:1 46:0 29:0 | ",
+			"ydotool key 29:1 47:1 47:0 29:0 | ",
+			"ydotool key 29:1 30:1 30:0 29:0 | ",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keys.Name(), func(t *testing.T) {
+			calls := fakeCommands(t, map[string]string{tt.keys.Name(): ""})
+
+			for _, send := range []func() error{tt.keys.Copy, tt.keys.Paste, tt.keys.SelectAll} {
+				if err := send(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := calls(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
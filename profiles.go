@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Profile bundles the settings that change together when switching between translation setups,
+// e.g. "customer email EN+JP with prefix" and "internal chat VN only"
+type Profile struct {
+	Name              string            `json:"name"`
+	Model             string            `json:"model,omitempty"`
+	SelectedLanguages []string          `json:"selected_languages"`
+	IncludePrefix     bool              `json:"include_prefix"`
+	ClipboardLanguage string            `json:"clipboard_language,omitempty"`
+	PromptStyle       string            `json:"prompt_style,omitempty"`
+	Hotkeys           map[string]string `json:"hotkeys,omitempty"`
+}
+
+// Find a profile by name, names are case-insensitive
+func findProfile(config Config, name string) (int, bool) {
+	for i, profile := range config.Profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Get the names of all profiles in the order they were created
+func profileNames(config Config) []string {
+	names := make([]string, len(config.Profiles))
+	for i, profile := range config.Profiles {
+		names[i] = profile.Name
+	}
+	return names
+}
+
+// Take a profile from the current settings. The profile switching hotkey is left out,
+// so switching never loses the way back.
+func profileFromConfig(config Config, name string) Profile {
+	hotkeys := maps.Clone(config.Hotkeys)
+	delete(hotkeys, actionNextProfile)
+	return Profile{
+		Name:              name,
+		Model:             config.Model,
+		SelectedLanguages: slices.Clone(config.SelectedLanguages),
+		IncludePrefix:     config.IncludePrefix,
+		ClipboardLanguage: config.ClipboardLanguage,
+		PromptStyle:       config.PromptStyle,
+		Hotkeys:           hotkeys,
+	}
+}
+
+// Save the current settings as a profile and make it the active one, an existing profile
+// with the same name is replaced
+func saveProfile(config *Config, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("profile name is required")
+	}
+
+	profile := profileFromConfig(*config, name)
+	if i, ok := findProfile(*config, name); ok {
+		profile.Name = config.Profiles[i].Name
+		config.Profiles[i] = profile
+	} else {
+		config.Profiles = append(config.Profiles, profile)
+	}
+	config.ActiveProfile = profile.Name
+	return nil
+}
+
+// Apply the settings of a profile to the config
+func switchProfile(config *Config, name string) error {
+	i, ok := findProfile(*config, name)
+	if !ok {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+	profile := config.Profiles[i]
+
+	if profile.Model != "" {
+		config.Model = profile.Model
+	}
+	config.SelectedLanguages = slices.Clone(profile.SelectedLanguages)
+	config.IncludePrefix = profile.IncludePrefix
+	if profile.ClipboardLanguage != "" {
+		config.ClipboardLanguage = profile.ClipboardLanguage
+	}
+	config.PromptStyle = profile.PromptStyle
+	// Actions the profile has no hotkey for keep their current one
+	if config.Hotkeys == nil {
+		config.Hotkeys = map[string]string{}
+	}
+	for action, accelerator := range profile.Hotkeys {
+		config.Hotkeys[action] = accelerator
+	}
+	config.ActiveProfile = profile.Name
+	enableUsedLanguages(config)
+	return nil
+}
+
+// Remove a profile, the current settings stay as they are
+func deleteProfile(config *Config, name string) error {
+	i, ok := findProfile(*config, name)
+	if !ok {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+	if strings.EqualFold(config.ActiveProfile, config.Profiles[i].Name) {
+		config.ActiveProfile = ""
+	}
+	config.Profiles = slices.Delete(config.Profiles, i, i+1)
+	return nil
+}
+
+// Keep the active profile in sync with settings changed while it is active
+func syncActiveProfile(config *Config) {
+	if config.ActiveProfile == "" {
+		return
+	}
+	i, ok := findProfile(*config, config.ActiveProfile)
+	if !ok {
+		config.ActiveProfile = ""
+		return
+	}
+	config.Profiles[i] = profileFromConfig(*config, config.Profiles[i].Name)
+}
+
+// Get the profile after the active one, wrapping around at the end
+func nextProfileName(config Config) (string, bool) {
+	if len(config.Profiles) == 0 {
+		return "", false
+	}
+	i, ok := findProfile(config, config.ActiveProfile)
+	if !ok {
+		return config.Profiles[0].Name, true
+	}
+	return config.Profiles[(i+1)%len(config.Profiles)].Name, true
+}
+
+// Switch to a profile by name and save the config
+func activateProfile(name string) error {
+	var switchErr error
+	err := updateConfig(func(config *Config) { switchErr = switchProfile(config, name) })
+	if switchErr != nil {
+		return switchErr
+	}
+	if err != nil {
+		return err
+	}
+	logf("👤 Switched to profile: %s\n", currentConfig().ActiveProfile)
+	return nil
+}
+
+// Switch to the next profile, used by the profile hotkey
+func activateNextProfile() {
+	name, ok := nextProfileName(currentConfig())
+	if !ok {
+		logln("⚠️ No profiles to switch between")
+		return
+	}
+	if err := activateProfile(name); err != nil {
+		logf("❌ Error switching profile: %v\n", err)
+		return
+	}
+	showNotification("Profile", fmt.Sprintf("Switched to profile: %s", name))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestSwitchProfileKeepsEmptyFields(t *testing.T) {
+	setConfig(Config{
+		Provider:          providerGemini,
+		Model:             "model-a",
+		SelectedLanguages: []string{"en"},
+		ClipboardLanguage: "vi",
+		Hotkeys:           map[string]string{actionTranslate: "ctrl+alt+1"},
+		Profiles: []Profile{
+			{Name: "A", Model: "model-a", SelectedLanguages: []string{"en"}, ClipboardLanguage: "vi", Hotkeys: map[string]string{actionTranslate: "ctrl+alt+1"}},
+			{Name: "B", SelectedLanguages: []string{"ja"}},
+		},
+		ActiveProfile: "A",
+	})
+	t.Cleanup(func() { setConfig(Config{}) })
+
+	if err := activateProfile("B"); err != nil {
+		t.Fatal(err)
+	}
+	config := currentConfig()
+	if config.ActiveProfile != "B" || config.SelectedLanguages[0] != "ja" {
+		t.Fatalf("active profile = %q, languages %v", config.ActiveProfile, config.SelectedLanguages)
+	}
+	i, _ := findProfile(config, "B")
+	if profile := config.Profiles[i]; profile.Model != "" || profile.ClipboardLanguage != "" || len(profile.Hotkeys) != 0 {
+		t.Errorf("profile B picked up the settings of A: %+v", profile)
+	}
+
+	// Changes made while B is active are kept in B
+	if err := updateConfig(func(config *Config) { config.Model = "model-b" }); err != nil {
+		t.Fatal(err)
+	}
+	config = currentConfig()
+	if config.Profiles[i].Model != "model-b" {
+		t.Errorf("profile B model = %q, want model-b", config.Profiles[i].Model)
+	}
+	if want := map[string]string{actionTranslate: "ctrl+alt+1"}; !maps.Equal(config.Profiles[0].Hotkeys, want) {
+		t.Errorf("profile A hotkeys = %v, want %v", config.Profiles[0].Hotkeys, want)
+	}
+}
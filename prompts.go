@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Built-in prompt templates, prompts/styles/<style>/<name>.tmpl
+//
+//go:embed prompts
+var builtinPromptFiles embed.FS
+
+// Names of the prompt templates used by the LLM based backends
+const (
+	promptTranslate      = "translate"       // Translate into one language
+	promptTranslateMulti = "translate_multi" // Translate into several languages, answered as a JSON object
+)
+
+// Prompt styles of the LLM based backends
+const (
+	promptStyleImproved  = "improved"  // Translate and rephrase to make the text clearer
+	promptStyleFaithful  = "faithful"  // Translate as literally as possible, e.g. for legal text or UI strings
+	promptStyleCasual    = "casual"    // Friendly chat tone
+	promptStyleFormal    = "formal"    // Polite business register
+	promptStyleTechnical = "technical" // Technical terminology, code and names unchanged
+)
+
+// Built-in styles in the order they are shown, styles from the prompts directory follow them
+var builtinPromptStyles = []string{promptStyleImproved, promptStyleFaithful, promptStyleCasual, promptStyleFormal, promptStyleTechnical}
+
+// promptData holds the variables of prompt templates, e.g. {{.Text}} and {{.Language}}
+type promptData struct {
+	Text           string     // The captured text
+	Language       string     // Prompt name of the first target language, e.g. "Japanese"
+	Languages      []string   // Prompt names of all target languages
+	Targets        []Language // All target languages with their codes, for prompts answered as JSON
+	SourceLanguage string     // Name of the detected language of the text, empty if unknown
+	Profile        string     // Name of the active profile, empty if none
+	Action         string     // Name of the action being run, empty outside actions
+	Style          string     // Prompt style
+}
+
+// promptSettings pick the prompt templates of a translation
+type promptSettings struct {
+	Style   string
+	Action  string
+	Profile string
+}
+
+// Get the prompt settings of a config snapshot
+func promptSettingsFor(config Config) promptSettings {
+	style := config.PromptStyle
+	if style == "" {
+		style = promptStyleImproved
+	}
+	return promptSettings{Style: style, Action: config.PromptAction, Profile: config.ActiveProfile}
+}
+
+// Functions available in prompt templates
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// "en (English), ja (Japanese)"
+	"languageList": func(targets []Language) string {
+		var languages []string
+		for _, target := range targets {
+			languages = append(languages, fmt.Sprintf("%s (%s)", target.Code, target.PromptName))
+		}
+		return strings.Join(languages, ", ")
+	},
+}
+
+// Parse a prompt template, name is used in error messages
+func parsePromptTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(text)
+}
+
+// Render a prompt template with the given variables
+func renderPrompt(name string, text string, data promptData) (string, error) {
+	tmpl, err := parsePromptTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", err
+	}
+	return prompt.String(), nil
+}
+
+// Get the prompt variables for a text and its target languages
+func newPromptData(config Config, text string, source string, targets []Language) promptData {
+	settings := promptSettingsFor(config)
+	data := promptData{Text: text, Targets: targets, Profile: settings.Profile, Action: settings.Action, Style: settings.Style}
+	if language, ok := findLanguage(config, source); ok && source != "" {
+		data.SourceLanguage = language.PromptName
+	}
+	for _, target := range targets {
+		data.Languages = append(data.Languages, target.PromptName)
+	}
+	if len(data.Languages) > 0 {
+		data.Language = data.Languages[0]
+	}
+	return data
+}
+
+// Directory with the user's prompt templates, next to config.json
+func userPromptsDir() string {
+	return filepath.Join(filepath.Dir(getConfigPath()), "prompts")
+}
+
+// Get the names of all prompt styles, built-in ones first, then the directories in prompts/styles
+func promptStyleNames() []string {
+	styles := slices.Clone(builtinPromptStyles)
+	entries, err := os.ReadDir(filepath.Join(userPromptsDir(), "styles"))
+	if err != nil {
+		return styles
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !contains(styles, entry.Name()) {
+			styles = append(styles, entry.Name())
+		}
+	}
+	return styles
+}
+
+// Check if a name can be used as a directory in the prompts directory
+func isPromptDirName(name string) bool {
+	return name != "" && filepath.IsLocal(name) && !strings.ContainsAny(name, `/\`)
+}
+
+// Find the template text of a prompt. User templates are searched from the most specific scope:
+// actions/<action>, languages/<code> (single language prompts only), profiles/<profile> and
+// styles/<style>, then the built-in template of the style is used.
+func loadPromptTemplate(name string, settings promptSettings, languageCode string) (source string, text string, err error) {
+	file := name + ".tmpl"
+	var candidates []string
+	if isPromptDirName(settings.Action) {
+		candidates = append(candidates, filepath.Join("actions", settings.Action, file))
+	}
+	if isPromptDirName(languageCode) {
+		candidates = append(candidates, filepath.Join("languages", languageCode, file))
+	}
+	if isPromptDirName(settings.Profile) {
+		candidates = append(candidates, filepath.Join("profiles", settings.Profile, file))
+	}
+	if isPromptDirName(settings.Style) {
+		candidates = append(candidates, filepath.Join("styles", settings.Style, file))
+	}
+
+	dir := userPromptsDir()
+	for _, candidate := range candidates {
+		source = filepath.Join(dir, candidate)
+		data, err := os.ReadFile(source)
+		if err == nil {
+			return source, string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return source, "", err
+		}
+	}
+
+	// Styles added by the user may only override some templates
+	style := settings.Style
+	if !contains(builtinPromptStyles, style) {
+		style = promptStyleImproved
+	}
+	source = path.Join("prompts", "styles", style, file)
+	data, err := builtinPromptFiles.ReadFile(source)
+	if err != nil {
+		return source, "", err
+	}
+	return source, string(data), nil
+}
+
+// Render a prompt template for the LLM based backends, languageCode picks language
+// overrides and is empty for prompts covering several languages
+func buildPrompt(name string, settings promptSettings, languageCode string, data promptData) (string, error) {
+	source, text, err := loadPromptTemplate(name, settings, languageCode)
+	if err != nil {
+		return "", fmt.Errorf("prompt template %s: %v", source, err)
+	}
+	data.Style, data.Action, data.Profile = settings.Style, settings.Action, settings.Profile
+	prompt, err := renderPrompt(source, text, data)
+	if err != nil {
+		return "", fmt.Errorf("prompt template %s: %v", source, err)
+	}
+	// Files end with a newline that is not part of the prompt
+	return strings.TrimSpace(prompt), nil
+}
+
+// Build the prompt for translating into one language
+func buildTranslationPrompt(settings promptSettings, text string, target Language) (string, error) {
+	data := promptData{Text: text, Language: target.PromptName, Languages: []string{target.PromptName}, Targets: []Language{target}}
+	return buildPrompt(promptTranslate, settings, target.Code, data)
+}
+
+// Build the prompt for translating into several languages at once
+func buildMultiTranslationPrompt(settings promptSettings, text string, targets []Language) (string, error) {
+	data := promptData{Text: text, Targets: targets}
+	for _, target := range targets {
+		data.Languages = append(data.Languages, target.PromptName)
+	}
+	if len(data.Languages) > 0 {
+		data.Language = data.Languages[0]
+	}
+	return buildPrompt(promptTranslateMulti, settings, "", data)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Rewrite the golden files with the current output: go test -run Prompt -update
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// Text with quotes and a newline, prompts must pass it on unchanged
+const promptTestText = "Hello \"world\",\nsee you at 5pm"
+
+// Compare output with a golden file in testdata/prompts
+func checkGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "prompts", name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run go test -run Prompt -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s:\n%s", name, path, got)
+	}
+}
+
+// Write a template into the user prompts directory, removed again when the test ends
+func writeUserPrompt(t *testing.T, file string, text string) {
+	t.Helper()
+	path := filepath.Join(userPromptsDir(), file)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(userPromptsDir()) })
+}
+
+func TestBuiltinPromptsGolden(t *testing.T) {
+	targets := []Language{testLanguage(t, "en"), testLanguage(t, "ja")}
+	for _, style := range builtinPromptStyles {
+		t.Run(style, func(t *testing.T) {
+			settings := promptSettings{Style: style}
+			prompt, err := buildTranslationPrompt(settings, promptTestText, targets[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, style+"."+promptTranslate, prompt)
+
+			prompt, err = buildMultiTranslationPrompt(settings, promptTestText, targets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, style+"."+promptTranslateMulti, prompt)
+		})
+	}
+}
+
+// The prompts of the improved and faithful styles before they moved to template files
+func legacyTranslationPrompt(text string, language string, style string) string {
+	if style == promptStyleFaithful {
+		return fmt.Sprintf("Please translate the following text to %s as faithfully as possible, keeping its meaning, tone, formatting and terminology without rephrasing. Return only the translated result without any additional explanation: \"%s\"", language, text)
+	}
+	return fmt.Sprintf("Please translate the following text to %s and improve/rephrase it to make it more clear, natural, and easy to understand. Return only the improved translated result without any additional explanation: \"%s\"", language, text)
+}
+
+func legacyMultiTranslationPrompt(text string, targets []Language, style string) string {
+	var languages []string
+	for _, target := range targets {
+		languages = append(languages, fmt.Sprintf("%s (%s)", target.Code, target.PromptName))
+	}
+	if style == promptStyleFaithful {
+		return fmt.Sprintf("Please translate the following text to each of these languages: %s, as faithfully as possible, keeping its meaning, tone, formatting and terminology without rephrasing. Return a JSON object whose keys are the language codes and whose values are only the translated results without any additional explanation: \"%s\"", strings.Join(languages, ", "), text)
+	}
+	return fmt.Sprintf("Please translate the following text to each of these languages: %s, and improve/rephrase each translation to make it more clear, natural, and easy to understand. Return a JSON object whose keys are the language codes and whose values are only the improved translated results without any additional explanation: \"%s\"", strings.Join(languages, ", "), text)
+}
+
+func TestDefaultPromptsMatchLegacy(t *testing.T) {
+	targets := []Language{testLanguage(t, "en"), testLanguage(t, "zh-Hant")}
+	for _, style := range []string{promptStyleImproved, promptStyleFaithful} {
+		t.Run(style, func(t *testing.T) {
+			// The default style of a config without prompt_style
+			config := Config{PromptStyle: style}
+			if style == promptStyleImproved {
+				config.PromptStyle = ""
+			}
+			settings := promptSettingsFor(config)
+
+			got, err := buildTranslationPrompt(settings, promptTestText, targets[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := legacyTranslationPrompt(promptTestText, targets[1].PromptName, style); got != want {
+				t.Errorf("translate prompt:\n got %q\nwant %q", got, want)
+			}
+
+			got, err = buildMultiTranslationPrompt(settings, promptTestText, targets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := legacyMultiTranslationPrompt(promptTestText, targets, style); got != want {
+				t.Errorf("translate_multi prompt:\n got %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPromptOverrideOrder(t *testing.T) {
+	settings := promptSettings{Style: promptStyleCasual, Action: "reply", Profile: "work"}
+	target := testLanguage(t, "ja")
+	build := func() string {
+		t.Helper()
+		prompt, err := buildTranslationPrompt(settings, promptTestText, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return prompt
+	}
+
+	// Most specific first: action, language, profile, style, then the built-in template
+	scopes := []string{
+		filepath.Join("actions", "reply"),
+		filepath.Join("languages", "ja"),
+		filepath.Join("profiles", "work"),
+		filepath.Join("styles", promptStyleCasual),
+	}
+	for _, scope := range scopes {
+		writeUserPrompt(t, filepath.Join(scope, promptTranslate+".tmpl"), scope+": {{.Language}} {{.Style}}\n")
+	}
+
+	for _, scope := range scopes {
+		if got, want := build(), scope+": Japanese casual"; got != want {
+			t.Errorf("prompt = %q, want %q", got, want)
+		}
+		if err := os.RemoveAll(filepath.Join(userPromptsDir(), scope)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	builtin, err := buildTranslationPrompt(promptSettings{Style: promptStyleCasual}, promptTestText, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := build(); got != builtin {
+		t.Errorf("prompt without overrides = %q, want the built-in %q", got, builtin)
+	}
+}
+
+func TestPromptOverrideScopes(t *testing.T) {
+	writeUserPrompt(t, filepath.Join("languages", "ja", promptTranslate+".tmpl"), "ja only")
+	writeUserPrompt(t, filepath.Join("styles", "pirate", promptTranslate+".tmpl"), "pirate {{.Text}}")
+
+	// Language overrides only apply to their language and to single language prompts
+	english, err := buildTranslationPrompt(promptSettings{Style: promptStyleImproved}, "Hi", testLanguage(t, "en"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if english == "ja only" {
+		t.Error("Japanese override used for English")
+	}
+	multi, err := buildMultiTranslationPrompt(promptSettings{Style: promptStyleImproved}, "Hi", []Language{testLanguage(t, "ja")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if multi == "ja only" {
+		t.Error("language override used for a multi-language prompt")
+	}
+
+	// A user style falls back to the improved templates it doesn't have
+	pirate := promptSettings{Style: "pirate"}
+	if got, _ := buildTranslationPrompt(pirate, "Hi", testLanguage(t, "en")); got != "pirate Hi" {
+		t.Errorf("pirate prompt = %q", got)
+	}
+	got, err := buildMultiTranslationPrompt(pirate, "Hi", []Language{testLanguage(t, "en")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := legacyMultiTranslationPrompt("Hi", []Language{testLanguage(t, "en")}, promptStyleImproved); got != want {
+		t.Errorf("pirate multi prompt = %q, want the improved one", got)
+	}
+	if !contains(promptStyleNames(), "pirate") {
+		t.Errorf("promptStyleNames() = %v, want pirate listed", promptStyleNames())
+	}
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Translator translates text into a target language of the registry
+type Translator interface {
+	Name() string
+	Translate(text string, target Language) (string, error)
+}
+
+// Supported translation providers
+const (
+	providerGemini         = "gemini"
+	providerOpenAI         = "openai"
+	providerOllama         = "ollama"
+	providerDeepL          = "deepl"
+	providerLibreTranslate = "libretranslate"
+)
+
+// Provider names shown in the provider dropdown
+var translationProviders = []string{
+	providerGemini,
+	providerOpenAI,
+	providerOllama,
+	providerDeepL,
+	providerLibreTranslate,
+}
+
+// Default API endpoints for each provider
+const (
+	defaultGeminiBaseURL         = "https://generativelanguage.googleapis.com/v1beta"
+	defaultOpenAIBaseURL         = "https://api.openai.com/v1"
+	defaultOllamaBaseURL         = "http://localhost:11434"
+	defaultDeepLBaseURL          = "https://api.deepl.com/v2"
+	defaultDeepLFreeBaseURL      = "https://api-free.deepl.com/v2"
+	defaultLibreTranslateBaseURL = "http://localhost:5000"
+)
+
+// Models offered in the model dropdown for each provider, Gemini models come from availableGeminiModels.
+// DeepL has no models, its entries are the model_type options of the API.
+var providerModels = map[string][]string{
+	providerOpenAI:         {"gpt-4o-mini", "gpt-4o", "gpt-4.1-mini", "gpt-4.1"},
+	providerOllama:         {"llama3.1", "qwen2.5", "gemma2", "mistral"},
+	providerDeepL:          {"latency_optimized", "quality_optimized", "prefer_quality_optimized"},
+	providerLibreTranslate: {"default"},
+}
+
+// HTTP client shared by all backends
+var translatorHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+// Get models for a provider, falling back to the Gemini list for unknown providers
+func modelsForProvider(provider string) []string {
+	if models, ok := providerModels[provider]; ok {
+		return models
+	}
+	return availableGeminiModels(currentConfig())
+}
+
+// Check if a provider needs an API key to work
+func providerRequiresAPIKey(provider string) bool {
+	switch provider {
+	case providerOllama, providerLibreTranslate:
+		return false
+	}
+	return true
+}
+
+// Get the API key configured for the active provider
+func providerAPIKey(config Config) string {
+	switch config.Provider {
+	case providerOpenAI:
+		return config.OpenAIAPIKey
+	case providerDeepL:
+		return config.DeepLAPIKey
+	case providerLibreTranslate:
+		return config.LibreTranslateAPIKey
+	case providerOllama:
+		return ""
+	}
+	return config.GeminiAPIKey
+}
+
+// Set the API key for the active provider
+func setProviderAPIKey(config *Config, key string) {
+	switch config.Provider {
+	case providerOpenAI:
+		config.OpenAIAPIKey = key
+	case providerDeepL:
+		config.DeepLAPIKey = key
+	case providerLibreTranslate:
+		config.LibreTranslateAPIKey = key
+	case providerOllama:
+		// Ollama runs locally without a key
+	default:
+		config.GeminiAPIKey = key
+	}
+}
+
+// Check if the config has everything the active provider needs
+func hasUsableAPIKey(config Config) bool {
+	if !providerRequiresAPIKey(config.Provider) {
+		return true
+	}
+	key := providerAPIKey(config)
+	return key != "" && key != "YOUR_GEMINI_API_KEY_HERE"
+}
+
+// Get the endpoint of the Gemini API, gemini_base_url or the public API
+func geminiBaseURL(config Config) string {
+	if config.GeminiBaseURL != "" {
+		return strings.TrimSuffix(config.GeminiBaseURL, "/")
+	}
+	return defaultGeminiBaseURL
+}
+
+// Create the translator for the provider selected in config
+func newTranslator(config Config) (Translator, error) {
+	model := config.Model
+	if model == "" {
+		model = modelsForProvider(config.Provider)[0]
+	}
+
+	switch config.Provider {
+	case providerGemini, "":
+		return &geminiTranslator{apiKey: config.GeminiAPIKey, model: model, baseURL: geminiBaseURL(config), prompts: promptSettingsFor(config)}, nil
+	case providerOpenAI:
+		baseURL := config.OpenAIBaseURL
+		if baseURL == "" {
+			baseURL = defaultOpenAIBaseURL
+		}
+		return &openAITranslator{apiKey: config.OpenAIAPIKey, model: model, baseURL: baseURL, prompts: promptSettingsFor(config)}, nil
+	case providerOllama:
+		baseURL := config.OllamaBaseURL
+		if baseURL == "" {
+			baseURL = defaultOllamaBaseURL
+		}
+		return &ollamaTranslator{model: model, baseURL: baseURL, prompts: promptSettingsFor(config)}, nil
+	case providerDeepL:
+		baseURL := config.DeepLBaseURL
+		if baseURL == "" {
+			baseURL = defaultDeepLBaseURL
+			// Free API keys end with ":fx" and use a separate endpoint
+			if strings.HasSuffix(config.DeepLAPIKey, ":fx") {
+				baseURL = defaultDeepLFreeBaseURL
+			}
+		}
+		return &deepLTranslator{apiKey: config.DeepLAPIKey, modelType: model, baseURL: baseURL}, nil
+	case providerLibreTranslate:
+		baseURL := config.LibreTranslateURL
+		if baseURL == "" {
+			baseURL = defaultLibreTranslateBaseURL
+		}
+		return &libreTranslator{apiKey: config.LibreTranslateAPIKey, baseURL: baseURL}, nil
+	}
+	return nil, fmt.Errorf("unknown translation provider: %s", config.Provider)
+}
+
+// Clean up the response text of a translation
+func cleanTranslation(text string) string {
+	result := strings.TrimSpace(text)
+
+	// Remove any potential quotes around the result
+	if len(result) >= 2 && strings.HasPrefix(result, "\"") && strings.HasSuffix(result, "\"") {
+		result = result[1 : len(result)-1]
+	}
+	return result
+}
+
+// Error for a non-200 response of a backend
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Body)
+}
+
+// Send a JSON request and decode the JSON response into out
+func postJSON(url string, headers map[string]string, payload any, out any) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return doJSONRequest("POST", url, headers, bytes.NewBuffer(jsonData), out)
+}
+
+// Send a GET request and decode the JSON response into out
+func getJSON(url string, headers map[string]string, out any) error {
+	return doJSONRequest("GET", url, headers, nil, out)
+}
+
+func doJSONRequest(method string, url string, headers map[string]string, body io.Reader, out any) error {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := translatorHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+
+	return json.Unmarshal(respBody, out)
+}
+
+// geminiTranslator uses the Gemini generateContent API
+type geminiTranslator struct {
+	apiKey  string
+	model   string
+	baseURL string
+	prompts promptSettings
+}
+
+func (g *geminiTranslator) Name() string { return providerGemini }
+
+func (g *geminiTranslator) Translate(text string, target Language) (string, error) {
+	prompt, err := buildTranslationPrompt(g.prompts, text, target)
+	if err != nil {
+		return "", err
+	}
+	result, err := g.generate(prompt, nil)
+	if err != nil {
+		return "", err
+	}
+	return cleanTranslation(result), nil
+}
+
+// Send any prompt to Gemini, used by prompt actions
+func (g *geminiTranslator) RunPrompt(prompt string) (string, error) {
+	result, err := g.generate(prompt, nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result), nil
+}
+
+// Translate text into all targets with one request, asking Gemini for a JSON object keyed by language code
+func (g *geminiTranslator) TranslateMulti(text string, targets []Language) (map[string]string, error) {
+	properties := map[string]any{}
+	var codes []string
+	for _, target := range targets {
+		properties[target.Code] = map[string]any{
+			"type":        "STRING",
+			"description": "Translation in " + target.PromptName,
+		}
+		codes = append(codes, target.Code)
+	}
+
+	generationConfig := &GeminiGenerationConfig{
+		ResponseMimeType: "application/json",
+		ResponseSchema: map[string]any{
+			"type":             "OBJECT",
+			"properties":       properties,
+			"required":         codes,
+			"propertyOrdering": codes,
+		},
+	}
+
+	prompt, err := buildMultiTranslationPrompt(g.prompts, text, targets)
+	if err != nil {
+		return nil, err
+	}
+	result, err := g.generate(prompt, generationConfig)
+	if err != nil {
+		return nil, err
+	}
+	return parseMultiTranslation(result, targets)
+}
+
+// Ask Gemini for the BCP-47 code of the language of a text
+func (g *geminiTranslator) DetectLanguage(text string) (string, error) {
+	prompt := fmt.Sprintf("Identify the language of the following text. Reply with only its BCP-47 language code (e.g. en, vi, ja, ko, zh-Hans, zh-Hant) and nothing else: \"%s\"", text)
+	result, err := g.generate(prompt, nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(strings.TrimSpace(result), "\"`."), nil
+}
+
+// Send a prompt to the generateContent API and return the text of the first candidate
+func (g *geminiTranslator) generate(prompt string, generationConfig *GeminiGenerationConfig) (string, error) {
+	url := fmt.Sprintf("%s/models/%s:generateContent", g.baseURL, g.model)
+	// Send the key in a header, URLs end up in logs and error messages
+	headers := map[string]string{"x-goog-api-key": g.apiKey}
+
+	reqBody := GeminiRequest{
+		Contents: []struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		}{
+			{
+				Parts: []struct {
+					Text string `json:"text"`
+				}{
+					{Text: prompt},
+				},
+			},
+		},
+		GenerationConfig: generationConfig,
+	}
+
+	var geminiResp GeminiResponse
+	if err := postJSON(url, headers, reqBody, &geminiResp); err != nil {
+		return "", err
+	}
+
+	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("no translation received")
+	}
+
+	return geminiResp.Candidates[0].Content.Parts[0].Text, nil
+}
+
+// Chat message for OpenAI-compatible and Ollama APIs
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAITranslator uses an OpenAI-compatible chat completions API
+type openAITranslator struct {
+	apiKey  string
+	model   string
+	baseURL string
+	prompts promptSettings
+}
+
+func (o *openAITranslator) Name() string { return providerOpenAI }
+
+func (o *openAITranslator) Translate(text string, target Language) (string, error) {
+	prompt, err := buildTranslationPrompt(o.prompts, text, target)
+	if err != nil {
+		return "", err
+	}
+	result, err := o.chat(prompt)
+	if err != nil {
+		return "", err
+	}
+	return cleanTranslation(result), nil
+}
+
+// Send any prompt to the chat completions API, used by prompt actions
+func (o *openAITranslator) RunPrompt(prompt string) (string, error) {
+	result, err := o.chat(prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result), nil
+}
+
+// Send a prompt as a single user message and return the reply
+func (o *openAITranslator) chat(prompt string) (string, error) {
+	reqBody := struct {
+		Model    string        `json:"model"`
+		Messages []chatMessage `json:"messages"`
+	}{
+		Model:    o.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	}
+
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		headers["Authorization"] = "Bearer " + o.apiKey
+	}
+
+	var chatResp struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := postJSON(strings.TrimSuffix(o.baseURL, "/")+"/chat/completions", headers, reqBody, &chatResp); err != nil {
+		return "", err
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("no translation received")
+	}
+
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+// ollamaTranslator uses the chat API of a local Ollama server
+type ollamaTranslator struct {
+	model   string
+	baseURL string
+	prompts promptSettings
+}
+
+func (o *ollamaTranslator) Name() string { return providerOllama }
+
+func (o *ollamaTranslator) Translate(text string, target Language) (string, error) {
+	prompt, err := buildTranslationPrompt(o.prompts, text, target)
+	if err != nil {
+		return "", err
+	}
+	result, err := o.chat(prompt)
+	if err != nil {
+		return "", err
+	}
+	return cleanTranslation(result), nil
+}
+
+// Send any prompt to the Ollama chat API, used by prompt actions
+func (o *ollamaTranslator) RunPrompt(prompt string) (string, error) {
+	result, err := o.chat(prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result), nil
+}
+
+// Send a prompt as a single user message and return the reply
+func (o *ollamaTranslator) chat(prompt string) (string, error) {
+	reqBody := struct {
+		Model    string        `json:"model"`
+		Messages []chatMessage `json:"messages"`
+		Stream   bool          `json:"stream"`
+	}{
+		Model:    o.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+		Stream:   false,
+	}
+
+	var chatResp struct {
+		Message chatMessage `json:"message"`
+	}
+	if err := postJSON(strings.TrimSuffix(o.baseURL, "/")+"/api/chat", nil, reqBody, &chatResp); err != nil {
+		return "", err
+	}
+
+	if chatResp.Message.Content == "" {
+		return "", fmt.Errorf("no translation received")
+	}
+
+	return chatResp.Message.Content, nil
+}
+
+// deepLTranslator uses the DeepL translate API
+type deepLTranslator struct {
+	apiKey    string
+	modelType string
+	baseURL   string
+}
+
+func (d *deepLTranslator) Name() string { return providerDeepL }
+
+func (d *deepLTranslator) Translate(text string, target Language) (string, error) {
+	if target.Code == "" {
+		return "", fmt.Errorf("language not supported by DeepL: %s", target.PromptName)
+	}
+	targetLang := strings.ToUpper(target.Code)
+	// DeepL requires a regional variant for English targets
+	if targetLang == "EN" {
+		targetLang = "EN-US"
+	}
+
+	reqBody := struct {
+		Text       []string `json:"text"`
+		TargetLang string   `json:"target_lang"`
+		ModelType  string   `json:"model_type,omitempty"`
+	}{
+		Text:       []string{text},
+		TargetLang: targetLang,
+		ModelType:  d.modelType,
+	}
+
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + d.apiKey}
+
+	var deepLResp struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := postJSON(strings.TrimSuffix(d.baseURL, "/")+"/translate", headers, reqBody, &deepLResp); err != nil {
+		return "", err
+	}
+
+	if len(deepLResp.Translations) == 0 {
+		return "", fmt.Errorf("no translation received")
+	}
+
+	return strings.TrimSpace(deepLResp.Translations[0].Text), nil
+}
+
+// libreTranslator uses a LibreTranslate server
+type libreTranslator struct {
+	apiKey  string
+	baseURL string
+}
+
+func (l *libreTranslator) Name() string { return providerLibreTranslate }
+
+func (l *libreTranslator) Translate(text string, target Language) (string, error) {
+	if target.Code == "" {
+		return "", fmt.Errorf("language not supported by LibreTranslate: %s", target.PromptName)
+	}
+	// LibreTranslate uses plain language codes, with "zt" for Traditional Chinese
+	code := strings.ToLower(strings.SplitN(target.Code, "-", 2)[0])
+	if strings.EqualFold(target.Code, "zh-Hant") {
+		code = "zt"
+	}
+
+	reqBody := struct {
+		Q      string `json:"q"`
+		Source string `json:"source"`
+		Target string `json:"target"`
+		Format string `json:"format"`
+		APIKey string `json:"api_key,omitempty"`
+	}{
+		Q:      text,
+		Source: "auto",
+		Target: code,
+		Format: "text",
+		APIKey: l.apiKey,
+	}
+
+	var libreResp struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := postJSON(strings.TrimSuffix(l.baseURL, "/")+"/translate", nil, reqBody, &libreResp); err != nil {
+		return "", err
+	}
+
+	if libreResp.TranslatedText == "" {
+		return "", fmt.Errorf("no translation received")
+	}
+
+	return strings.TrimSpace(libreResp.TranslatedText), nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// A backend under test and what a successful exchange with it looks like
+type translatorTestCase struct {
+	provider string
+	path     string                                                   // Path the request must be sent to
+	check    func(t *testing.T, r *http.Request, body map[string]any) // Checks headers and body of the request
+	response string                                                   // Body of a successful response, translating to "Xin chào"
+}
+
+var translatorTestCases = []translatorTestCase{
+	{
+		provider: providerGemini,
+		path:     "/models/test-model:generateContent",
+		check: func(t *testing.T, r *http.Request, body map[string]any) {
+			if got := r.Header.Get("x-goog-api-key"); got != "gemini-key" {
+				t.Errorf("x-goog-api-key = %q", got)
+			}
+			if strings.Contains(r.URL.RawQuery, "gemini-key") {
+				t.Errorf("API key sent in the URL: %s", r.URL)
+			}
+			checkPromptContains(t, body["contents"], "Hello", "Vietnamese")
+		},
+		response: `{"candidates":[{"content":{"parts":[{"text":" \"Xin chào\"\n"}]}}]}`,
+	},
+	{
+		provider: providerOpenAI,
+		path:     "/chat/completions",
+		check: func(t *testing.T, r *http.Request, body map[string]any) {
+			if got := r.Header.Get("Authorization"); got != "Bearer openai-key" {
+				t.Errorf("Authorization = %q", got)
+			}
+			if body["model"] != "test-model" {
+				t.Errorf("model = %v", body["model"])
+			}
+			checkPromptContains(t, body["messages"], "Hello", "Vietnamese")
+		},
+		response: `{"choices":[{"message":{"role":"assistant","content":"Xin chào"}}]}`,
+	},
+	{
+		provider: providerOllama,
+		path:     "/api/chat",
+		check: func(t *testing.T, r *http.Request, body map[string]any) {
+			if body["stream"] != false {
+				t.Errorf("stream = %v", body["stream"])
+			}
+			checkPromptContains(t, body["messages"], "Hello", "Vietnamese")
+		},
+		response: `{"message":{"role":"assistant","content":"Xin chào"}}`,
+	},
+	{
+		provider: providerDeepL,
+		path:     "/translate",
+		check: func(t *testing.T, r *http.Request, body map[string]any) {
+			if got := r.Header.Get("Authorization"); got != "DeepL-Auth-Key deepl-key" {
+				t.Errorf("Authorization = %q", got)
+			}
+			if body["target_lang"] != "VI" {
+				t.Errorf("target_lang = %v", body["target_lang"])
+			}
+		},
+		response: `{"translations":[{"text":"Xin chào"}]}`,
+	},
+	{
+		provider: providerLibreTranslate,
+		path:     "/translate",
+		check: func(t *testing.T, r *http.Request, body map[string]any) {
+			if body["target"] != "vi" || body["q"] != "Hello" || body["api_key"] != "libre-key" {
+				t.Errorf("body = %v", body)
+			}
+		},
+		response: `{"translatedText":"Xin chào"}`,
+	},
+}
+
+// Create the translator of a provider with every endpoint pointing at a local stand-in
+func newTestTranslator(t *testing.T, provider string, handler http.HandlerFunc) Translator {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	translator, err := newTranslator(Config{
+		Provider:             provider,
+		Model:                "test-model",
+		GeminiAPIKey:         "gemini-key",
+		GeminiBaseURL:        server.URL,
+		OpenAIAPIKey:         "openai-key",
+		OpenAIBaseURL:        server.URL,
+		OllamaBaseURL:        server.URL,
+		DeepLAPIKey:          "deepl-key",
+		DeepLBaseURL:         server.URL,
+		LibreTranslateURL:    server.URL,
+		LibreTranslateAPIKey: "libre-key",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return translator
+}
+
+// Check that a JSON value sent to an LLM contains all parts, wherever the prompt is nested
+func checkPromptContains(t *testing.T, value any, parts ...string) {
+	t.Helper()
+	data, _ := json.Marshal(value)
+	for _, part := range parts {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("prompt %s does not contain %q", data, part)
+		}
+	}
+}
+
+func TestTranslatorSuccess(t *testing.T) {
+	for _, tc := range translatorTestCases {
+		t.Run(tc.provider, func(t *testing.T) {
+			translator := newTestTranslator(t, tc.provider, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != tc.path {
+					t.Errorf("request = %s %s, want POST %s", r.Method, r.URL.Path, tc.path)
+				}
+				var body map[string]any
+				data, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(data, &body); err != nil {
+					t.Errorf("request body is not JSON: %v", err)
+				}
+				tc.check(t, r, body)
+				io.WriteString(w, tc.response)
+			})
+
+			if translator.Name() != tc.provider {
+				t.Errorf("Name() = %q", translator.Name())
+			}
+			got, err := translator.Translate("Hello", testLanguage(t, "vi"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != "Xin chào" {
+				t.Errorf("Translate() = %q, want %q", got, "Xin chào")
+			}
+		})
+	}
+}
+
+func TestTranslatorHTTPError(t *testing.T) {
+	for _, tc := range translatorTestCases {
+		t.Run(tc.provider, func(t *testing.T) {
+			translator := newTestTranslator(t, tc.provider, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				io.WriteString(w, `{"error":"rate limited"}`+"\n")
+			})
+
+			_, err := translator.Translate("Hello", testLanguage(t, "vi"))
+			var statusErr *httpStatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("Translate() error = %v, want an httpStatusError", err)
+			}
+			if statusErr.StatusCode != http.StatusTooManyRequests || statusErr.Body != `{"error":"rate limited"}` {
+				t.Errorf("error = %d %q", statusErr.StatusCode, statusErr.Body)
+			}
+			if reason := translationFailureReason(err); reason != "429" {
+				t.Errorf("translationFailureReason() = %q", reason)
+			}
+		})
+	}
+}
+
+func TestTranslatorEmptyResponse(t *testing.T) {
+	for _, tc := range translatorTestCases {
+		t.Run(tc.provider, func(t *testing.T) {
+			translator := newTestTranslator(t, tc.provider, func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `{}`)
+			})
+
+			got, err := translator.Translate("Hello", testLanguage(t, "vi"))
+			if err == nil || !strings.Contains(err.Error(), "no translation received") {
+				t.Errorf("Translate() = %q, %v, want a no translation error", got, err)
+			}
+		})
+	}
+}
+
+func TestTranslatorTargetCodes(t *testing.T) {
+	tests := []struct {
+		provider string
+		code     string
+		field    string
+		want     string
+	}{
+		{providerDeepL, "en", "target_lang", "EN-US"},
+		{providerDeepL, "zh-Hans", "target_lang", "ZH-HANS"},
+		{providerLibreTranslate, "zh-Hans", "target", "zh"},
+		{providerLibreTranslate, "zh-Hant", "target", "zt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.code, func(t *testing.T) {
+			var got any
+			translator := newTestTranslator(t, tt.provider, func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				json.NewDecoder(r.Body).Decode(&body)
+				got = body[tt.field]
+				io.WriteString(w, `{"translations":[{"text":"ok"}],"translatedText":"ok"}`)
+			})
+
+			if _, err := translator.Translate("Hello", testLanguage(t, tt.code)); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %v, want %s", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslatorUnsupportedLanguage(t *testing.T) {
+	for _, provider := range []string{providerDeepL, providerLibreTranslate} {
+		t.Run(provider, func(t *testing.T) {
+			translator := newTestTranslator(t, provider, func(w http.ResponseWriter, r *http.Request) {
+				t.Error("request sent for a language without a code")
+			})
+			if _, err := translator.Translate("Hello", Language{Name: "Klingon", PromptName: "Klingon"}); err == nil {
+				t.Error("Translate() to a language without a code succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a problem with one config field, Field is its JSON name
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors lists every problem found in a config
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, err := range v {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Get the messages of one field joined in a single line, empty when the field is valid
+func (v ValidationErrors) For(field string) string {
+	var messages []string
+	for _, err := range v {
+		if err.Field == field {
+			messages = append(messages, err.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Check the config for problems that would only show up when a hotkey is pressed.
+// Returns nil when the config is valid.
+func (c Config) Validate() ValidationErrors {
+	var errs ValidationErrors
+	add := func(field string, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !contains(translationProviders, c.Provider) {
+		add("provider", "unknown provider %q, use one of %s", c.Provider, strings.Join(translationProviders, ", "))
+	} else {
+		if !hasUsableAPIKey(c) {
+			add(c.Provider+"_api_key", "an API key is required for %s", c.Provider)
+		}
+		if c.Model != "" && !isKnownModel(c, c.Model) {
+			add("model", "unknown model %q for %s", c.Model, c.Provider)
+		}
+	}
+
+	if len(c.SelectedLanguages) == 0 {
+		add("selected_languages", "select at least one language")
+	}
+	for _, code := range c.SelectedLanguages {
+		if _, ok := findLanguage(c, code); !ok {
+			add("selected_languages", "unknown language code %q", code)
+		}
+	}
+	if _, ok := findLanguage(c, c.ClipboardLanguage); !ok {
+		add("clipboard_language", "unknown language code %q", c.ClipboardLanguage)
+	}
+	if c.ToggleLanguage != "" {
+		if _, ok := findLanguage(c, c.ToggleLanguage); !ok {
+			add("toggle_language", "unknown language code %q", c.ToggleLanguage)
+		}
+	}
+	if c.SameLanguageMode != "" && !contains(sameLanguageModes, c.SameLanguageMode) {
+		add("same_language_mode", "unknown mode %q, use one of %s", c.SameLanguageMode, strings.Join(sameLanguageModes, ", "))
+	}
+
+	for _, language := range c.CustomLanguages {
+		if strings.TrimSpace(language.Code) == "" || language.Name == "" {
+			add("custom_languages", "every custom language needs a code and a name")
+		}
+	}
+
+	if _, err := parseHotkeys(c.Hotkeys); err != nil {
+		add("hotkeys", "%v", err)
+	}
+	validateActions(c, add)
+
+	if c.PromptStyle != "" && !contains(promptStyleNames(), c.PromptStyle) {
+		add("prompt_style", "unknown prompt style %q, use one of %s", c.PromptStyle, strings.Join(promptStyleNames(), ", "))
+	}
+	for i, profile := range c.Profiles {
+		if strings.TrimSpace(profile.Name) == "" {
+			add("profiles", "profile %d has no name", i+1)
+		} else if first, _ := findProfile(c, profile.Name); first != i {
+			add("profiles", "profile name %q is used twice", profile.Name)
+		}
+		for _, code := range profile.SelectedLanguages {
+			if _, ok := findLanguage(c, code); !ok {
+				add("profiles", "profile %q: unknown language code %q", profile.Name, code)
+			}
+		}
+	}
+	if c.ActiveProfile != "" {
+		if _, ok := findProfile(c, c.ActiveProfile); !ok {
+			add("active_profile", "unknown profile %q", c.ActiveProfile)
+		}
+	}
+
+	if c.APIEnabled && len(c.APIToken) < minAPITokenLength {
+		add("api_enabled", "the API needs an api_token of at least %d characters", minAPITokenLength)
+	}
+	if c.APIPort < 0 || c.APIPort > 65535 {
+		add("api_port", "must be between 1 and 65535, or 0 for the default")
+	}
+
+	if c.CopyTimeoutMs < 0 {
+		add("copy_timeout_ms", "must not be negative")
+	}
+	if c.TranslationWorkers < 0 {
+		add("translation_workers", "must not be negative")
+	}
+
+	return errs
+}
+
+// Check if the provider of the config offers a model.
+// Local and custom OpenAI-compatible servers can run any model, their list is only a suggestion.
+func isKnownModel(c Config, model string) bool {
+	if c.Provider == providerOllama || (c.Provider == providerOpenAI && c.OpenAIBaseURL != "") {
+		return true
+	}
+	models := providerModels[c.Provider]
+	if c.Provider == providerGemini {
+		models = availableGeminiModels(c)
+	}
+	return contains(models, model)
+}
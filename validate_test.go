@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateActionModel(t *testing.T) {
+	tests := []struct {
+		provider string
+		model    string
+		valid    bool
+	}{
+		{providerGemini, geminiModels[0], true},
+		{providerGemini, "gpt-4o", false},
+		{providerDeepL, "gemini-2.5-pro", false},
+		{providerDeepL, "quality_optimized", true},
+		{providerLibreTranslate, "gpt-4o", false},
+		// Local servers run any model they have
+		{providerOllama, "my-finetune", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.model, func(t *testing.T) {
+			config := Config{
+				Provider: tt.provider,
+				Actions: []Action{{
+					Name: "summarize", Capture: captureSelection, Transform: transformPrompt,
+					Prompt: "Summarize: {{.Text}}", Model: tt.model, Output: outputPreview,
+				}},
+			}
+			message := config.Validate().For("actions")
+			if tt.valid && message != "" {
+				t.Errorf("model rejected: %s", message)
+			}
+			if !tt.valid && !strings.Contains(message, "unknown model") {
+				t.Errorf("actions error = %q, want an unknown model error", message)
+			}
+		})
+	}
+}